@@ -0,0 +1,11 @@
+module github.com/miru-smart-technologies/Scripts/go/pkg
+
+go 1.22.2
+
+require (
+	github.com/google/uuid v1.6.0
+	golang.org/x/text v0.21.0
+	resty.dev/v3 v3.0.0-beta.3
+)
+
+require golang.org/x/net v0.33.0 // indirect
@@ -0,0 +1,469 @@
+package portal
+
+import (
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"resty.dev/v3"
+)
+
+// PortalClient is the part of the Portal API the scripts use.
+// The Check* methods return whether the record exists and, if so, its ID.
+type PortalClient interface {
+	ListChemicals() ([]PortalChemical, error)
+	CheckIfChemicalExists(name string) (bool, string, error)
+	CheckIfChemicalWithCasExists(cas string) (bool, string, error)
+	CheckIfChemicalWithNameAndCasExists(name string, cas string) (bool, string, error)
+	CreateChemical(pChemical PayloadChemical) (string, error)
+	DeleteChemical(id string) error
+
+	ListChemicalRecipes() ([]PortalChemicalRecipe, error)
+	CheckIfChemicalRecipeExists(name string, chemicalID string) (bool, string, error)
+	CreateChemicalRecipe(pRecipe PayloadChemicalRecipe) (string, error)
+	DeleteChemicalRecipe(id string) error
+
+	CheckIfChemicalInstanceExists(ciid int64) (bool, string, error)
+	CreateChemicalInstance(pInstance PayloadChemicalInstance) (string, error)
+	DeleteChemicalInstance(id string) error
+
+	CheckIfSupplierExists(name string) (bool, string, error)
+	CreateSupplier(pSupplier PayloadSupplier) (string, error)
+	DeleteSupplier(id string) error
+
+	CheckIfLocationExists(name string) (bool, string, error)
+	CreateLocation(pLocation PayloadLocation) (string, error)
+	DeleteLocation(id string) error
+
+	CheckIfUserExists(userID string) (bool, error)
+	CheckIfUserWithNameExists(name string) (bool, string, error)
+}
+
+// Config holds what is needed to talk to a Portal
+type Config struct {
+	BaseURL  string        // e.g. http://192.168.2.2:8092
+	Timeout  time.Duration // per request, 0 for no timeout
+	NotFound NotFoundMode  // how the Portal reports a missing record, see ProbeNotFoundMode
+}
+
+// RestyClient implements PortalClient on top of resty
+type RestyClient struct {
+	client       *resty.Client
+	notFound     NotFoundMode
+	hasCasLookup atomic.Bool // the CAS number lookup found a chemical, so the Portal has the route
+}
+
+var _ PortalClient = (*RestyClient)(nil)
+
+// NewPortalClient creates a PortalClient for the Portal described by cfg
+func NewPortalClient(cfg Config) *RestyClient {
+	client := resty.New().SetBaseURL(cfg.BaseURL)
+	if cfg.Timeout > 0 {
+		client.SetTimeout(cfg.Timeout)
+	}
+	return &RestyClient{client: client, notFound: cfg.NotFound}
+}
+
+// ListChemicals returns all chemicals of the Portal
+func (c *RestyClient) ListChemicals() ([]PortalChemical, error) {
+	var result []PortalChemical
+
+	resp, err := c.client.R().
+		SetResult(&result).
+		Get("/chemicals")
+
+	if err != nil {
+		return nil, &APIError{Op: "list chemicals", Err: err}
+	}
+
+	if resp.StatusCode() == 200 {
+		return result, nil
+	}
+
+	return nil, newResponseError("list chemicals", resp)
+}
+
+func (c *RestyClient) CheckIfChemicalExists(name string) (bool, string, error) {
+	found, chemical, err := c.getChemicalByName("check if chemical exists", name)
+	return found, chemical.ID, err
+}
+
+// CheckIfChemicalWithNameAndCasExists looks a chemical up by its name and only reports it if it has no CAS number
+// or the given one, e.g. to reuse for a row with a CAS number the chemical an earlier row without one created.
+// The Portal returns one chemical per name, so a chemical of the same name with another CAS number hides the others.
+func (c *RestyClient) CheckIfChemicalWithNameAndCasExists(name string, cas string) (bool, string, error) {
+	found, chemical, err := c.getChemicalByName("check if chemical with name and CAS number exists", name)
+	if !found {
+		return false, "", err
+	}
+	if existing := strings.TrimSpace(chemical.SafetyInfo.CasNumber); existing != "" && existing != strings.TrimSpace(cas) {
+		return false, "", nil
+	}
+	return true, chemical.ID, nil
+}
+
+func (c *RestyClient) getChemicalByName(op string, name string) (bool, PortalChemical, error) {
+	var result PortalChemical
+
+	resp, err := c.client.R().
+		SetResult(&result).
+		Get("/chemicals/name?" + textQuery("name", name))
+
+	if err != nil {
+		return false, PortalChemical{}, &APIError{Op: op, Err: err}
+	}
+
+	// the old API returns 500 if the chemical is not found, the updated one 404
+	if c.isNotFound(resp) {
+		return false, PortalChemical{}, nil
+	}
+
+	if resp.StatusCode() == 200 {
+		return true, result, nil
+	}
+
+	return false, PortalChemical{}, newResponseError(op, resp)
+}
+
+// CheckIfChemicalWithCasExists looks a chemical up by its CAS number, e.g. 71-36-3.
+// A 404 can also mean the Portal doesn't have the route, so it returns ErrNoCasLookup for it unless the Portal
+// is strict and the route has found a chemical before.
+func (c *RestyClient) CheckIfChemicalWithCasExists(cas string) (bool, string, error) {
+	var result PortalChemical
+
+	resp, err := c.client.R().
+		SetResult(&result).
+		Get("/chemicals/cas?" + textQuery("cas", cas))
+
+	if err != nil {
+		return false, "", &APIError{Op: "check if chemical with CAS number exists", Err: err}
+	}
+
+	if resp.StatusCode() == 404 && (c.notFound != NotFoundStrict || !c.hasCasLookup.Load()) {
+		return false, "", ErrNoCasLookup
+	}
+
+	if c.isNotFound(resp) {
+		return false, "", nil
+	}
+
+	if resp.StatusCode() == 200 {
+		c.hasCasLookup.Store(true)
+		return true, result.ID, nil
+	}
+
+	return false, "", newResponseError("check if chemical with CAS number exists", resp)
+}
+
+// ListChemicalRecipes returns the recipes of all chemicals of the Portal
+func (c *RestyClient) ListChemicalRecipes() ([]PortalChemicalRecipe, error) {
+	var result []PortalChemicalRecipe
+
+	resp, err := c.client.R().
+		SetResult(&result).
+		Get("/recipes")
+
+	if err != nil {
+		return nil, &APIError{Op: "list chemical recipes", Err: err}
+	}
+
+	if resp.StatusCode() == 200 {
+		return result, nil
+	}
+
+	return nil, newResponseError("list chemical recipes", resp)
+}
+
+func (c *RestyClient) CheckIfChemicalRecipeExists(name string, chemicalID string) (bool, string, error) {
+	/**
+	 * the reason why we are checking by looking up all the recipes given a chemical ID and see if title matches
+	 * is cuz we don't have a GET API to check if a recipe exists by recipe name and chemical ID
+	 */
+	var result []PortalChemicalRecipe
+
+	resp, err := c.client.R().
+		SetResult(&result).
+		SetPathParam("id", chemicalID).
+		Get("/chemicals/{id}/recipes")
+
+	if err != nil {
+		return false, "", &APIError{Op: "check if chemical recipe exists", Err: err}
+	}
+
+	if c.isNotFound(resp) {
+		return false, "", nil
+	}
+
+	if resp.StatusCode() == 200 {
+		// titles are stored in NFC, but one created before that, or by someone else, may not be
+		for _, recipe := range result {
+			if NormaliseText(recipe.Title) == NormaliseText(name) {
+				return true, recipe.ID, nil
+			}
+		}
+		return false, "", nil
+	}
+
+	return false, "", newResponseError("check if chemical recipe exists", resp)
+}
+
+func (c *RestyClient) CreateChemical(pChemical PayloadChemical) (string, error) {
+	// stored in NFC, like the lookups send it, so the record is found by the name it was created with
+	pChemical.Name = NormaliseText(pChemical.Name)
+	var result PortalChemical
+
+	resp, err := c.client.R().
+		SetHeader("Content-Type", "application/json").
+		SetBody(pChemical).
+		SetResult(&result).
+		Post("/chemicals")
+
+	if err != nil {
+		return "", &APIError{Op: "create new chemical", Err: err}
+	}
+
+	if resp.StatusCode() == 201 {
+		return result.ID, nil
+	}
+
+	return "", newResponseError("create new chemical", resp)
+}
+
+func (c *RestyClient) CreateChemicalRecipe(pRecipe PayloadChemicalRecipe) (string, error) {
+	pRecipe.Title = NormaliseText(pRecipe.Title)
+	var result PortalChemicalRecipe
+
+	resp, err := c.client.R().
+		SetHeader("Content-Type", "application/json").
+		SetBody(pRecipe).
+		SetResult(&result).
+		Post("/recipes/")
+
+	if err != nil {
+		return "", &APIError{Op: "create new chemical recipe", Err: err}
+	}
+
+	if resp.StatusCode() == 201 {
+		return result.ID, nil
+	}
+
+	return "", newResponseError("create new chemical recipe", resp)
+}
+
+func (c *RestyClient) CheckIfSupplierExists(name string) (bool, string, error) {
+	var result PortalSupplier
+
+	resp, err := c.client.R().
+		SetResult(&result).
+		Get("/suppliers/name?" + textQuery("name", name))
+
+	if err != nil {
+		return false, "", &APIError{Op: "check if supplier exists", Err: err}
+	}
+
+	// same as chemicals - 500 or 404 if the supplier is not found, depending on the API version
+	if c.isNotFound(resp) {
+		return false, "", nil
+	}
+
+	if resp.StatusCode() == 200 {
+		return true, result.ID, nil
+	}
+
+	return false, "", newResponseError("check if supplier exists", resp)
+}
+
+func (c *RestyClient) CreateSupplier(pSupplier PayloadSupplier) (string, error) {
+	pSupplier.Name = NormaliseText(pSupplier.Name)
+	var result PortalSupplier
+
+	resp, err := c.client.R().
+		SetHeader("Content-Type", "application/json").
+		SetBody(pSupplier).
+		SetResult(&result).
+		Post("/suppliers")
+
+	if err != nil {
+		return "", &APIError{Op: "create new supplier", Err: err}
+	}
+
+	if resp.StatusCode() == 201 {
+		return result.ID, nil
+	}
+
+	return "", newResponseError("create new supplier", resp)
+}
+
+func (c *RestyClient) CheckIfLocationExists(name string) (bool, string, error) {
+	var result PortalLocation
+
+	resp, err := c.client.R().
+		SetResult(&result).
+		Get("/locations/name?" + textQuery("name", name))
+
+	if err != nil {
+		return false, "", &APIError{Op: "check if location exists", Err: err}
+	}
+
+	// same as chemicals - 500 or 404 if the location is not found, depending on the API version
+	if c.isNotFound(resp) {
+		return false, "", nil
+	}
+
+	if resp.StatusCode() == 200 {
+		return true, result.ID, nil
+	}
+
+	return false, "", newResponseError("check if location exists", resp)
+}
+
+func (c *RestyClient) CreateLocation(pLocation PayloadLocation) (string, error) {
+	pLocation.Name = NormaliseText(pLocation.Name)
+	var result PortalLocation
+
+	resp, err := c.client.R().
+		SetHeader("Content-Type", "application/json").
+		SetBody(pLocation).
+		SetResult(&result).
+		Post("/locations")
+
+	if err != nil {
+		return "", &APIError{Op: "create new location", Err: err}
+	}
+
+	if resp.StatusCode() == 201 {
+		return result.ID, nil
+	}
+
+	return "", newResponseError("create new location", resp)
+}
+
+func (c *RestyClient) CheckIfUserExists(userID string) (bool, error) {
+	var result PortalUser
+
+	resp, err := c.client.R().
+		SetResult(&result).
+		SetPathParam("id", userID).
+		Get("/users/{id}")
+
+	if err != nil {
+		return false, &APIError{Op: "check if user exists", Err: err}
+	}
+
+	// same as chemicals - 500 or 404 if the user is not found, depending on the API version
+	if c.isNotFound(resp) {
+		return false, nil
+	}
+
+	if resp.StatusCode() == 200 {
+		return true, nil
+	}
+
+	return false, newResponseError("check if user exists", resp)
+}
+
+func (c *RestyClient) CheckIfUserWithNameExists(name string) (bool, string, error) {
+	var result PortalUser
+
+	resp, err := c.client.R().
+		SetResult(&result).
+		Get("/users/name?" + textQuery("name", name))
+
+	if err != nil {
+		return false, "", &APIError{Op: "check if user exists", Err: err}
+	}
+
+	if c.isNotFound(resp) {
+		return false, "", nil
+	}
+
+	if resp.StatusCode() == 200 {
+		return true, result.ID, nil
+	}
+
+	return false, "", newResponseError("check if user exists", resp)
+}
+
+// CheckIfChemicalInstanceExists looks an instance up by its CIID and returns its UUID. Like the other lookups by
+// something else than the ID it is a query, so /instances/{id} always takes the UUID, e.g. to delete the instance.
+func (c *RestyClient) CheckIfChemicalInstanceExists(ciid int64) (bool, string, error) {
+	var result PortalChemicalInstance
+
+	resp, err := c.client.R().
+		SetResult(&result).
+		SetQueryParam("ciid", strconv.FormatInt(ciid, 10)).
+		Get("/instances/ciid")
+
+	if err != nil {
+		return false, "", &APIError{Op: "check if chemical instance exists", Err: err}
+	}
+
+	// same as chemicals - 500 or 404 if the instance is not found, depending on the API version
+	if c.isNotFound(resp) {
+		return false, "", nil
+	}
+
+	if resp.StatusCode() == 200 {
+		return true, result.UUID.String(), nil
+	}
+
+	return false, "", newResponseError("check if chemical instance exists", resp)
+}
+
+func (c *RestyClient) CreateChemicalInstance(pInstance PayloadChemicalInstance) (string, error) {
+	var result PortalChemicalInstance
+
+	resp, err := c.client.R().
+		SetHeader("Content-Type", "application/json").
+		SetBody(pInstance).
+		SetResult(&result).
+		Post("/instances")
+
+	if err != nil {
+		return "", &APIError{Op: "create new chemical instance", Err: err}
+	}
+
+	if resp.StatusCode() == 201 {
+		return result.UUID.String(), nil
+	}
+
+	return "", newResponseError("create new chemical instance", resp)
+}
+
+// delete deletes a record by its ID; path has an {id} placeholder, so the ID is escaped like every other path segment
+func (c *RestyClient) delete(recordType string, path string, id string) error {
+	resp, err := c.client.R().
+		SetPathParam("id", id).
+		Delete(path)
+
+	if err != nil {
+		return &APIError{Op: "delete " + recordType, Err: err}
+	}
+
+	if resp.StatusCode() == 200 || resp.StatusCode() == 204 {
+		return nil
+	}
+
+	return newResponseError("delete "+recordType, resp)
+}
+
+func (c *RestyClient) DeleteChemical(id string) error {
+	return c.delete("chemical", "/chemicals/{id}", id)
+}
+
+func (c *RestyClient) DeleteChemicalRecipe(id string) error {
+	return c.delete("chemical recipe", "/recipes/{id}", id)
+}
+
+func (c *RestyClient) DeleteChemicalInstance(id string) error {
+	return c.delete("chemical instance", "/instances/{id}", id)
+}
+
+func (c *RestyClient) DeleteSupplier(id string) error {
+	return c.delete("supplier", "/suppliers/{id}", id)
+}
+
+func (c *RestyClient) DeleteLocation(id string) error {
+	return c.delete("location", "/locations/{id}", id)
+}
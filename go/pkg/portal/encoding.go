@@ -0,0 +1,22 @@
+package portal
+
+import (
+	"net/url"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// NormaliseText brings a name into Unicode NFC, so a name typed with a combining accent ("é" as e + ´)
+// is the same string as one pasted with the precomposed letter
+func NormaliseText(s string) string {
+	return norm.NFC.String(s)
+}
+
+// textQuery builds the query string of a lookup by a name or other text. The value is NFC normalised and
+// escaped as a query value, except that a space is %20 rather than + - a server that unescapes the query
+// like a path would keep the + and look up the wrong name. Commas, parentheses, "/", "+" and "®" are all
+// percent-encoded, so the value arrives as it was sent whichever way the server unescapes it.
+func textQuery(key string, value string) string {
+	return key + "=" + strings.ReplaceAll(url.QueryEscape(NormaliseText(value)), "+", "%20")
+}
@@ -0,0 +1,110 @@
+package portal
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+var specialNames = []string{
+	"ESTANE® AG 8451",
+	"4-(methylthio)benzyl alcohol",
+	"N,N-dimethylformamide",
+	"α-terpineol",
+	"2′-deoxyadenosine",
+	"sodium hydroxide 1 M / 0.1 M",
+	"(+)-camphor",
+	"50% ethanol & water",
+}
+
+func TestLookupsSendNamesUnchanged(t *testing.T) {
+	for _, unescape := range []struct {
+		name string
+		fn   func(string) (string, error)
+	}{
+		{"query", url.QueryUnescape},
+		{"path", url.PathUnescape},
+	} {
+		t.Run(unescape.name, func(t *testing.T) {
+			var received string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				// a server that unescapes the query string as a whole, one way or the other
+				query, err := unescape.fn(r.URL.RawQuery)
+				if err != nil {
+					t.Errorf("unescape %q: %v", r.URL.RawQuery, err)
+				}
+				received = query
+				http.Error(w, "not found", http.StatusInternalServerError)
+			}))
+			defer server.Close()
+			client := NewPortalClient(Config{BaseURL: server.URL})
+
+			for _, name := range specialNames {
+				if _, _, err := client.CheckIfChemicalExists(name); err != nil {
+					t.Fatal(err)
+				}
+				if received != "name="+name {
+					t.Errorf("looked up %q, server got %q", name, received)
+				}
+			}
+		})
+	}
+}
+
+func TestLookupsAndCreatesUseNFC(t *testing.T) {
+	decomposed := "e\u0301thyl acetate" // e + combining acute accent
+	precomposed := "\u00e9thyl acetate"
+
+	var lookedUp, created string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			var payload PayloadChemical
+			json.NewDecoder(r.Body).Decode(&payload)
+			created = payload.Name
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"id":"chemical-1"}`))
+			return
+		}
+		lookedUp = r.URL.Query().Get("name")
+		http.Error(w, "not found", http.StatusInternalServerError)
+	}))
+	defer server.Close()
+	client := NewPortalClient(Config{BaseURL: server.URL})
+
+	client.CheckIfChemicalExists(decomposed)
+	client.CreateChemical(PayloadChemical{Name: decomposed})
+	if lookedUp != precomposed || created != precomposed {
+		t.Errorf("looked up %q and created %q, want both %q", lookedUp, created, precomposed)
+	}
+}
+
+func TestRecipeTitlesAreComparedInNFC(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[{"id":"recipe-1","name":"\u00e9thyl acetate 99%"}]`))
+	}))
+	defer server.Close()
+	client := NewPortalClient(Config{BaseURL: server.URL})
+
+	found, id, err := client.CheckIfChemicalRecipeExists("e\u0301thyl acetate 99%", "chemical-1")
+	if !found || id != "recipe-1" || err != nil {
+		t.Errorf("found %v %q, err %v, want the decomposed title to find recipe-1", found, id, err)
+	}
+}
+
+func TestPathSegmentsAreEscaped(t *testing.T) {
+	var path string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.EscapedPath()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+	client := NewPortalClient(Config{BaseURL: server.URL})
+
+	client.DeleteChemical("a/b c")
+	if path != "/chemicals/a%2Fb%20c" {
+		t.Errorf("deleted %q, want the ID escaped as one path segment", path)
+	}
+}
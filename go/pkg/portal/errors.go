@@ -0,0 +1,90 @@
+package portal
+
+import (
+	"errors"
+	"fmt"
+	"net"
+
+	"resty.dev/v3"
+)
+
+// ErrNoCasLookup is returned by CheckIfChemicalWithCasExists when the CAS number lookup answered 404 and the Portal
+// may not have it: a legacy Portal never answers 404 for a missing record, a strict one does for a missing route too.
+// The chemical may still exist, so look it up by name instead.
+var ErrNoCasLookup = errors.New("the Portal may not have a CAS number lookup")
+
+// ErrorKind classifies a failed Portal call so callers can decide whether trying again can help
+type ErrorKind int
+
+const (
+	ErrorKindTransport ErrorKind = iota // no response: connection refused, reset or timed out
+	ErrorKindServer                     // 5xx response
+	ErrorKindClient                     // 4xx response - the request itself is wrong, trying again won't help
+	ErrorKindOther                      // any other unexpected response, e.g. 200 where 201 was expected
+)
+
+func (k ErrorKind) String() string {
+	switch k {
+	case ErrorKindTransport:
+		return "transport error"
+	case ErrorKindServer:
+		return "server error"
+	case ErrorKindClient:
+		return "client error"
+	}
+	return "unexpected response"
+}
+
+// APIError is a failed Portal call: a transport error, or a response with an unexpected status code.
+// Note that with NotFoundLegacy the lookups treat a 500 as "not found", so they never return it as an error;
+// only the creates and deletes can fail with a 500. With NotFoundStrict a 500 of a lookup is an error too.
+type APIError struct {
+	Op         string // what was being done, e.g. "check if chemical exists"
+	StatusCode int    // 0 for a transport error
+	Body       string
+	Err        error // the transport error, nil if there was a response
+}
+
+func newResponseError(op string, resp *resty.Response) *APIError {
+	return &APIError{Op: op, StatusCode: resp.StatusCode(), Body: resp.String()}
+}
+
+func (e *APIError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("failed to %s: %v", e.Op, e.Err)
+	}
+	return fmt.Sprintf("failed to %s, status code: %d, response: %s", e.Op, e.StatusCode, e.Body)
+}
+
+func (e *APIError) Unwrap() error {
+	return e.Err
+}
+
+// Kind classifies the error
+func (e *APIError) Kind() ErrorKind {
+	switch {
+	case e.Err != nil:
+		return ErrorKindTransport
+	case e.StatusCode >= 500:
+		return ErrorKindServer
+	case e.StatusCode >= 400:
+		return ErrorKindClient
+	}
+	return ErrorKindOther
+}
+
+// notSent reports whether the request never reached the Portal, e.g. the connection was refused,
+// so even a create can safely be sent again
+func (e *APIError) notSent() bool {
+	var opErr *net.OpError
+	return errors.As(e.Err, &opErr) && opErr.Op == "dial"
+}
+
+// ErrorKindOf classifies an error returned by a PortalClient; errors that are not an *APIError are ErrorKindOther
+func ErrorKindOf(err error) ErrorKind {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Kind()
+	}
+	return ErrorKindOther
+}
@@ -0,0 +1,154 @@
+// Package portal is the client for the Alchemy Portal API shared by the Go scripts.
+package portal
+
+import (
+	"github.com/google/uuid"
+)
+
+// --- database models ---
+
+type PortalChemical struct { // <<<<<<<
+	ID              string           `json:"id"`
+	Name            string           `json:"name"`          // 1-butanol
+	Formula         string           `json:"formula"`       // nice to have
+	StateOfMatter   string           `json:"stateOfMatter"` // solid, liquid, gas (how to know that???) maybe
+	IsProduct       bool             `json:"isProduct"`
+	Type            string           `json:"type"` //IGNORE whatever is not on alchemy portal
+	Description     string           `json:"description"`
+	Colour          string           `json:"colour"`
+	MolecularWeight float64          `json:"molecularWeight"` // g/mol, 120.18
+	Density         float64          `json:"density"`         // g/cm3
+	SafetyInfo      PortalSafetyInfo `json:"safetyInfo"`      // JSON object
+}
+
+type PortalSafetyInfo struct { // <<<<<<<
+	CasNumber    string            `json:"casNumber"`    // 71-36-3
+	UNNumber     *string           `json:"unNumber"`     // UN1120, null if not dangerous goods
+	HazardClass  *string           `json:"hazardClass"`  // 3, or 8 (6.1) with a subsidiary class
+	PackingGroup *string           `json:"packingGroup"` // I, II or III
+	SafetyNotes  string            `json:"safetyNotes"`  // put other safety info here: "GHS Flammable liquid cateogry: 3;"
+	GhsHazards   []PortalGhsHazard `json:"ghsHazards"`   // GHS classification, one entry per hazard class and category
+}
+
+type PortalGhsHazard struct {
+	HazardClass string `json:"hazardClass"` // Acute toxicity (oral)
+	Category    string `json:"category"`    // 4, 1B, 2A, Type C, 1.4
+}
+
+type PortalComponent struct { // <<<<<<<
+	RecipeUUID  uuid.UUID `json:"recipeUUID"`
+	RecipeTitle string    `json:"recipeTitle"`
+	Fraction    float64   `json:"fraction"`
+}
+
+type PortalChemicalRecipe struct { // <<<<<<<
+	ID           string    `json:"id"`
+	Title        string    `json:"name"`         // 99.9%
+	ChemicalUUID uuid.UUID `json:"chemicalUUID"` // UUID of butuanol
+	Description  string    `json:"description"`  // ignore
+	Tags         []string  `json:"tags"`         // ignore
+	// ProcessSet   *PortalProcessSet `json:"processSet"`
+	Components []PortalComponent `json:"components"` // emtpy if it's a supplied chemical - list of inputs
+}
+type PortalChemicalInstance struct {
+	UUID             uuid.UUID                 `json:"uuid"`
+	ID               int64                     `json:"id"`
+	RecipeUUID       uuid.UUID                 `json:"recipeUUID"`
+	Amount           float64                   `json:"amount"`
+	Unit             string                    `json:"unit"` // kg or L
+	Owner            uuid.UUID                 `json:"owner"`
+	Components       []PortalComponentInstance `json:"inputComponentInstances"`
+	HomeLocationUUID uuid.UUID                 `json:"locationUUID"`
+	SupplierUUID     uuid.UUID                 `json:"supplierUUID"`
+	ParentUUID       uuid.UUID                 `json:"parentUUID"`
+
+	ManufactureDate string  `json:"manufactureDate"`
+	ExpirationDate  string  `json:"expirationDate"`
+	LotNumber       string  `json:"lotNumber"`
+	Label           string  `json:"label"`
+	GrossWeight     float64 `json:"grossWeight"`
+	NetWeight       float64 `json:"netWeight"`
+	Notes           string  `json:"notes"`
+}
+
+type PortalSupplier struct {
+	ID   string `json:"id"`
+	Name string `json:"name"` // Sigma-Aldrich
+}
+
+type PortalLocation struct {
+	ID   string `json:"id"`
+	Name string `json:"name"` // FC1
+}
+
+type PortalUser struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type PortalComponentInstance struct {
+	ChemicalInstanceUUID uuid.UUID `json:"chemicalInstanceUUID"`
+	Amount               float64   `json:"amount"`
+	Unit                 string    `json:"unit"`
+}
+
+// --- payload models ---
+
+type PayloadSafetyInfo struct { // <<<<<<<
+	CasNumber    string            `json:"casNumber"`    // 71-36-3
+	UNNumber     *string           `json:"unNumber"`     // UN1120, null if not dangerous goods
+	HazardClass  *string           `json:"hazardClass"`  // 3, or 8 (6.1) with a subsidiary class
+	PackingGroup *string           `json:"packingGroup"` // I, II or III
+	SafetyNotes  string            `json:"safetyNotes"`  // put other safety info here: "GHS Flammable liquid cateogry: 3;"
+	GhsHazards   []PortalGhsHazard `json:"ghsHazards"`
+}
+
+type PayloadChemical struct { // <<<<<<<
+	Name            string           `json:"name"` // 1-butanol
+	Description     string           `json:"description"`
+	StateOfMatter   *string          `json:"stateOfMatter"`   // solid, liquid, gas - null if unknown
+	Colour          *string          `json:"colour"`          // null if unknown
+	MolecularWeight *float64         `json:"molecularWeight"` // g/mol, null if unknown
+	Density         *float64         `json:"density"`         // g/cm3, null if unknown
+	SafetyInfo      PortalSafetyInfo `json:"safetyInfo"`      // JSON object
+}
+
+type PayloadComponent struct { // <<<<<<<
+	RecipeUUID  uuid.UUID `json:"recipeUUID"`
+	RecipeTitle string    `json:"recipeTitle"`
+	Fraction    float64   `json:"fraction"`
+}
+
+type PayloadChemicalRecipe struct { // <<<<<<<
+	Title        string            `json:"name"`         // 99.9%
+	ChemicalUUID uuid.UUID         `json:"chemicalUUID"` // UUID of butuanol
+	Components   []PortalComponent `json:"components"`   // emtpy if it's a supplied chemical - list of inputs
+}
+
+type PayloadSupplier struct {
+	Name string `json:"name"` // Sigma-Aldrich
+}
+
+type PayloadLocation struct {
+	Name string `json:"name"` // FC1
+}
+
+type PayloadChemicalInstance struct {
+	ID               int64                     `json:"id"`
+	RecipeUUID       uuid.UUID                 `json:"recipeUUID"`
+	Amount           float64                   `json:"amount"`
+	Unit             string                    `json:"unit"` // kg or L
+	Owner            uuid.UUID                 `json:"owner"`
+	Components       []PortalComponentInstance `json:"inputComponentInstances"`
+	HomeLocationUUID uuid.UUID                 `json:"locationUUID"`
+	SupplierUUID     uuid.UUID                 `json:"supplierUUID"`
+	ParentUUID       uuid.UUID                 `json:"parentUUID"`
+
+	ManufactureDate string  `json:"manufactureDate"`
+	ExpirationDate  string  `json:"expirationDate"`
+	LotNumber       string  `json:"lotNumber"`
+	Label           string  `json:"label"`
+	GrossWeight     float64 `json:"grossWeight"`
+	NetWeight       float64 `json:"netWeight"`
+	Notes           string  `json:"notes"`
+}
@@ -0,0 +1,73 @@
+package portal
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+	"resty.dev/v3"
+)
+
+// NotFoundMode says how the Portal answers the lookup of a record that doesn't exist
+type NotFoundMode int
+
+const (
+	// NotFoundLegacy is the old API: a missing record is a 500. A real server error looks the same,
+	// so a lookup that failed is taken for a missing record and the record is created again.
+	NotFoundLegacy NotFoundMode = iota
+	// NotFoundStrict is the updated API: a missing record is a 404 and a 500 is an error
+	NotFoundStrict
+)
+
+func (m NotFoundMode) String() string {
+	if m == NotFoundStrict {
+		return "strict"
+	}
+	return "legacy"
+}
+
+// ParseNotFoundMode parses "strict" or "legacy"
+func ParseNotFoundMode(s string) (NotFoundMode, error) {
+	switch s {
+	case "strict":
+		return NotFoundStrict, nil
+	case "legacy":
+		return NotFoundLegacy, nil
+	}
+	return NotFoundLegacy, fmt.Errorf("unknown not-found mode %q - expected strict or legacy", s)
+}
+
+// isNotFound reports whether the response of a lookup means the record doesn't exist
+func (c *RestyClient) isNotFound(resp *resty.Response) bool {
+	if c.notFound == NotFoundStrict {
+		return resp.StatusCode() == 404
+	}
+	return resp.StatusCode() == 500
+}
+
+// NotFoundMode returns how the client reads the response of a lookup
+func (c *RestyClient) NotFoundMode() NotFoundMode {
+	return c.notFound
+}
+
+// SetNotFoundMode sets how the client reads the response of a lookup, e.g. to what ProbeNotFoundMode found
+func (c *RestyClient) SetNotFoundMode(mode NotFoundMode) {
+	c.notFound = mode
+}
+
+// ProbeNotFoundMode looks up a chemical that can't exist to find out how the Portal answers for a missing record
+func (c *RestyClient) ProbeNotFoundMode() (NotFoundMode, error) {
+	resp, err := c.client.R().
+		Get("/chemicals/name?" + textQuery("name", "not-found-probe-"+uuid.NewString()))
+
+	if err != nil {
+		return NotFoundLegacy, &APIError{Op: "probe how missing records are reported", Err: err}
+	}
+
+	switch resp.StatusCode() {
+	case 404:
+		return NotFoundStrict, nil
+	case 500:
+		return NotFoundLegacy, nil
+	}
+	return NotFoundLegacy, newResponseError("probe how missing records are reported", resp)
+}
@@ -0,0 +1,110 @@
+package portal
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNotFoundModes(t *testing.T) {
+	tests := []struct {
+		mode     NotFoundMode
+		status   int
+		kind     ErrorKind // of the error, if any
+		wantErr  bool
+		attempts int
+	}{
+		{NotFoundLegacy, 500, 0, false, 1},
+		{NotFoundLegacy, 404, ErrorKindClient, true, 1},
+		{NotFoundStrict, 404, 0, false, 1},
+		// the lookup failed, so it is retried instead of taking the chemical for missing and creating it again
+		{NotFoundStrict, 500, ErrorKindServer, true, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode.String(), func(t *testing.T) {
+			server, _ := statusServer(t, tt.status)
+			client := NewRetryClient(NewPortalClient(Config{BaseURL: server.URL, NotFound: tt.mode}), RetryPolicy{MaxAttempts: 3})
+			client.sleep = func(time.Duration) {}
+
+			found, _, err := client.CheckIfChemicalExists("1-butanol")
+			if found || (err != nil) != tt.wantErr {
+				t.Fatalf("%d: found %v, err %v, want error %v", tt.status, found, err, tt.wantErr)
+			}
+			if err != nil && ErrorKindOf(err) != tt.kind {
+				t.Errorf("%d: error kind %v, want %v", tt.status, ErrorKindOf(err), tt.kind)
+			}
+			if attempts := client.TakeAttempts(); attempts != tt.attempts {
+				t.Errorf("%d: %d attempts, want %d", tt.status, attempts, tt.attempts)
+			}
+		})
+	}
+}
+
+func TestProbeNotFoundMode(t *testing.T) {
+	tests := []struct {
+		status  int
+		mode    NotFoundMode
+		wantErr bool
+	}{
+		{404, NotFoundStrict, false},
+		{500, NotFoundLegacy, false},
+		{200, NotFoundLegacy, true}, // a chemical that can't exist was found
+		{503, NotFoundLegacy, true},
+	}
+	for _, tt := range tests {
+		server, _ := statusServer(t, tt.status)
+
+		mode, err := NewPortalClient(Config{BaseURL: server.URL}).ProbeNotFoundMode()
+		if mode != tt.mode || (err != nil) != tt.wantErr {
+			t.Errorf("%d: mode %v, err %v, want %v, error %v", tt.status, mode, err, tt.mode, tt.wantErr)
+		}
+	}
+}
+
+func TestCasLookupWithoutRoute(t *testing.T) {
+	server, requests := statusServer(t, 404)
+	client := testRetryClient(server.URL)
+
+	// a legacy Portal answers 404 only for a route it doesn't have
+	found, _, err := client.CheckIfChemicalWithCasExists("71-36-3")
+	if found || !errors.Is(err, ErrNoCasLookup) || *requests != 1 {
+		t.Errorf("legacy: found %v, err %v after %d requests, want ErrNoCasLookup without retries", found, err, *requests)
+	}
+
+	// a strict Portal answers 404 for a missing route too, until the route has found a chemical
+	server, _ = statusServer(t, 404, 200, 404)
+	strict := NewPortalClient(Config{BaseURL: server.URL, NotFound: NotFoundStrict})
+	for i, want := range []struct {
+		found bool
+		err   error
+	}{{false, ErrNoCasLookup}, {true, nil}, {false, nil}} {
+		found, _, err := strict.CheckIfChemicalWithCasExists("71-36-3")
+		if found != want.found || !errors.Is(err, want.err) || (want.err == nil && err != nil) {
+			t.Errorf("strict lookup %d: found %v, err %v, want found %v, err %v", i+1, found, err, want.found, want.err)
+		}
+	}
+}
+
+func TestChemicalWithNameAndCasLookup(t *testing.T) {
+	for _, tt := range []struct {
+		body  string
+		found bool
+	}{
+		{`{"id":"chemical-1","safetyInfo":{"casNumber":""}}`, true},
+		{`{"id":"chemical-1","safetyInfo":{"casNumber":"71-36-3"}}`, true},
+		{`{"id":"chemical-1","safetyInfo":{"casNumber":"64-17-5"}}`, false},
+	} {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(tt.body))
+		}))
+		defer server.Close()
+
+		found, id, err := NewPortalClient(Config{BaseURL: server.URL}).CheckIfChemicalWithNameAndCasExists("1-butanol", "71-36-3")
+		if found != tt.found || err != nil || (found && id != "chemical-1") {
+			t.Errorf("%s: found %v %q, err %v, want found %v", tt.body, found, id, err, tt.found)
+		}
+	}
+}
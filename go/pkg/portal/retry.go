@@ -0,0 +1,244 @@
+package portal
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+)
+
+// RetryPolicy says how often a failed Portal call is tried and how long to wait in between.
+// The wait doubles with every attempt, up to MaxDelay, and a random part of it is used (full jitter)
+// so that many calls failing together don't all come back at the same moment.
+type RetryPolicy struct {
+	MaxAttempts int           // including the first one, 1 for no retries
+	BaseDelay   time.Duration // upper bound of the wait before the second attempt
+	MaxDelay    time.Duration // upper bound of any wait
+}
+
+var DefaultRetryPolicy = RetryPolicy{MaxAttempts: 4, BaseDelay: 500 * time.Millisecond, MaxDelay: 10 * time.Second}
+
+// delay returns the wait before the given attempt (2 for the first retry)
+func (p RetryPolicy) delay(attempt int) time.Duration {
+	limit := p.MaxDelay
+	if shift := attempt - 2; shift < 30 && p.BaseDelay<<shift < p.MaxDelay {
+		limit = p.BaseDelay << shift
+	}
+	if limit <= 0 {
+		return 0
+	}
+	return rand.N(limit + 1)
+}
+
+// RetryClient wraps a PortalClient and tries failed calls again according to a RetryPolicy.
+//
+// Lookups and deletes are tried again after a transport error or a 5xx response. Creates are not idempotent,
+// so they are only tried again when the request can't have reached the Portal: the connection was refused,
+// or a 502/503 came from the proxy in front of it. 4xx responses are never tried again. A missing record is
+// not an error (see NotFoundMode), so it is not tried again either.
+//
+// A RetryClient counts the attempts of its last call and must not be shared between goroutines.
+type RetryClient struct {
+	PortalClient
+	policy   RetryPolicy
+	sleep    func(time.Duration)
+	attempts int
+}
+
+var _ PortalClient = (*RetryClient)(nil)
+
+func NewRetryClient(client PortalClient, policy RetryPolicy) *RetryClient {
+	if policy.MaxAttempts < 1 {
+		policy.MaxAttempts = 1
+	}
+	return &RetryClient{PortalClient: client, policy: policy, sleep: time.Sleep}
+}
+
+// TakeAttempts returns the number of attempts of the last call and resets it, so a call is only reported once.
+// It is 0 if there was no call since the last TakeAttempts.
+func (c *RetryClient) TakeAttempts() int {
+	attempts := c.attempts
+	c.attempts = 0
+	return attempts
+}
+
+func (c *RetryClient) do(idempotent bool, call func() error) error {
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = call()
+		c.attempts = attempt
+		if err == nil || attempt >= c.policy.MaxAttempts || !retryable(err, idempotent) {
+			break
+		}
+		c.sleep(c.policy.delay(attempt + 1))
+	}
+
+	if err != nil && c.attempts > 1 {
+		return fmt.Errorf("%w (after %d attempts)", err, c.attempts)
+	}
+	return err
+}
+
+// retryable reports whether trying a failed call again can help and is safe
+func retryable(err error, idempotent bool) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+
+	switch apiErr.Kind() {
+	case ErrorKindTransport:
+		return idempotent || apiErr.notSent()
+	case ErrorKindServer:
+		return idempotent || apiErr.StatusCode == 502 || apiErr.StatusCode == 503
+	}
+	return false
+}
+
+func (c *RetryClient) ListChemicals() (chemicals []PortalChemical, err error) {
+	err = c.do(true, func() error {
+		chemicals, err = c.PortalClient.ListChemicals()
+		return err
+	})
+	return chemicals, err
+}
+
+func (c *RetryClient) CheckIfChemicalExists(name string) (res bool, id string, err error) {
+	err = c.do(true, func() error {
+		res, id, err = c.PortalClient.CheckIfChemicalExists(name)
+		return err
+	})
+	return res, id, err
+}
+
+func (c *RetryClient) CheckIfChemicalWithCasExists(cas string) (res bool, id string, err error) {
+	err = c.do(true, func() error {
+		res, id, err = c.PortalClient.CheckIfChemicalWithCasExists(cas)
+		return err
+	})
+	return res, id, err
+}
+
+func (c *RetryClient) CheckIfChemicalWithNameAndCasExists(name string, cas string) (res bool, id string, err error) {
+	err = c.do(true, func() error {
+		res, id, err = c.PortalClient.CheckIfChemicalWithNameAndCasExists(name, cas)
+		return err
+	})
+	return res, id, err
+}
+
+func (c *RetryClient) CreateChemical(pChemical PayloadChemical) (id string, err error) {
+	err = c.do(false, func() error {
+		id, err = c.PortalClient.CreateChemical(pChemical)
+		return err
+	})
+	return id, err
+}
+
+func (c *RetryClient) DeleteChemical(id string) error {
+	return c.do(true, func() error { return c.PortalClient.DeleteChemical(id) })
+}
+
+func (c *RetryClient) ListChemicalRecipes() (recipes []PortalChemicalRecipe, err error) {
+	err = c.do(true, func() error {
+		recipes, err = c.PortalClient.ListChemicalRecipes()
+		return err
+	})
+	return recipes, err
+}
+
+func (c *RetryClient) CheckIfChemicalRecipeExists(name string, chemicalID string) (res bool, id string, err error) {
+	err = c.do(true, func() error {
+		res, id, err = c.PortalClient.CheckIfChemicalRecipeExists(name, chemicalID)
+		return err
+	})
+	return res, id, err
+}
+
+func (c *RetryClient) CreateChemicalRecipe(pRecipe PayloadChemicalRecipe) (id string, err error) {
+	err = c.do(false, func() error {
+		id, err = c.PortalClient.CreateChemicalRecipe(pRecipe)
+		return err
+	})
+	return id, err
+}
+
+func (c *RetryClient) DeleteChemicalRecipe(id string) error {
+	return c.do(true, func() error { return c.PortalClient.DeleteChemicalRecipe(id) })
+}
+
+func (c *RetryClient) CheckIfChemicalInstanceExists(ciid int64) (res bool, id string, err error) {
+	err = c.do(true, func() error {
+		res, id, err = c.PortalClient.CheckIfChemicalInstanceExists(ciid)
+		return err
+	})
+	return res, id, err
+}
+
+func (c *RetryClient) CreateChemicalInstance(pInstance PayloadChemicalInstance) (id string, err error) {
+	err = c.do(false, func() error {
+		id, err = c.PortalClient.CreateChemicalInstance(pInstance)
+		return err
+	})
+	return id, err
+}
+
+func (c *RetryClient) DeleteChemicalInstance(id string) error {
+	return c.do(true, func() error { return c.PortalClient.DeleteChemicalInstance(id) })
+}
+
+func (c *RetryClient) CheckIfSupplierExists(name string) (res bool, id string, err error) {
+	err = c.do(true, func() error {
+		res, id, err = c.PortalClient.CheckIfSupplierExists(name)
+		return err
+	})
+	return res, id, err
+}
+
+func (c *RetryClient) CreateSupplier(pSupplier PayloadSupplier) (id string, err error) {
+	err = c.do(false, func() error {
+		id, err = c.PortalClient.CreateSupplier(pSupplier)
+		return err
+	})
+	return id, err
+}
+
+func (c *RetryClient) DeleteSupplier(id string) error {
+	return c.do(true, func() error { return c.PortalClient.DeleteSupplier(id) })
+}
+
+func (c *RetryClient) CheckIfLocationExists(name string) (res bool, id string, err error) {
+	err = c.do(true, func() error {
+		res, id, err = c.PortalClient.CheckIfLocationExists(name)
+		return err
+	})
+	return res, id, err
+}
+
+func (c *RetryClient) CreateLocation(pLocation PayloadLocation) (id string, err error) {
+	err = c.do(false, func() error {
+		id, err = c.PortalClient.CreateLocation(pLocation)
+		return err
+	})
+	return id, err
+}
+
+func (c *RetryClient) DeleteLocation(id string) error {
+	return c.do(true, func() error { return c.PortalClient.DeleteLocation(id) })
+}
+
+func (c *RetryClient) CheckIfUserExists(userID string) (res bool, err error) {
+	err = c.do(true, func() error {
+		res, err = c.PortalClient.CheckIfUserExists(userID)
+		return err
+	})
+	return res, err
+}
+
+func (c *RetryClient) CheckIfUserWithNameExists(name string) (res bool, id string, err error) {
+	err = c.do(true, func() error {
+		res, id, err = c.PortalClient.CheckIfUserWithNameExists(name)
+		return err
+	})
+	return res, id, err
+}
@@ -0,0 +1,129 @@
+package portal
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// statusServer answers the requests to the Portal with the given status codes in turn, then with the last one
+func statusServer(t *testing.T, statuses ...int) (*httptest.Server, *int) {
+	t.Helper()
+
+	var mu sync.Mutex
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		status := statuses[min(requests, len(statuses)-1)]
+		requests++
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write([]byte(`{"id":"chemical-1"}`))
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func testRetryClient(baseURL string) *RetryClient {
+	client := NewRetryClient(NewPortalClient(Config{BaseURL: baseURL, Timeout: 5 * time.Second}), RetryPolicy{MaxAttempts: 3})
+	client.sleep = func(time.Duration) {}
+	return client
+}
+
+func TestRetryClientLookups(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []int
+		found    bool
+		wantErr  bool
+		attempts int
+	}{
+		{"found", []int{200}, true, false, 1},
+		{"bad gateway then found", []int{502, 200}, true, false, 2},
+		{"not found is not retried", []int{500}, false, false, 1},
+		{"bad request is not retried", []int{400}, false, true, 1},
+		{"unavailable every time", []int{503}, false, true, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, requests := statusServer(t, tt.statuses...)
+			client := testRetryClient(server.URL)
+
+			found, _, err := client.CheckIfChemicalExists("1-butanol")
+			if found != tt.found || (err != nil) != tt.wantErr {
+				t.Errorf("found %v, err %v, want found %v, error %v", found, err, tt.found, tt.wantErr)
+			}
+			if attempts := client.TakeAttempts(); attempts != tt.attempts || *requests != tt.attempts {
+				t.Errorf("%d attempts, %d requests, want %d", attempts, *requests, tt.attempts)
+			}
+		})
+	}
+}
+
+func TestRetryClientCreates(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []int
+		kind     ErrorKind // of the error, if any
+		wantErr  bool
+		attempts int
+	}{
+		{"created", []int{201}, 0, false, 1},
+		{"unavailable then created", []int{503, 201}, 0, false, 2},
+		{"internal error may have created it", []int{500, 201}, ErrorKindServer, true, 1},
+		{"bad request", []int{400}, ErrorKindClient, true, 1},
+		{"unexpected status", []int{200}, ErrorKindOther, true, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, requests := statusServer(t, tt.statuses...)
+			client := testRetryClient(server.URL)
+
+			id, err := client.CreateChemical(PayloadChemical{Name: "1-butanol"})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("id %q, err %v, want error %v", id, err, tt.wantErr)
+			}
+			if err != nil && ErrorKindOf(err) != tt.kind {
+				t.Errorf("error kind %v, want %v", ErrorKindOf(err), tt.kind)
+			}
+			if attempts := client.TakeAttempts(); attempts != tt.attempts || *requests != tt.attempts {
+				t.Errorf("%d attempts, %d requests, want %d", attempts, *requests, tt.attempts)
+			}
+		})
+	}
+}
+
+func TestRetryClientRetriesRefusedConnections(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close() // nothing listens on the port any more
+	client := testRetryClient(server.URL)
+
+	_, err := client.CreateChemical(PayloadChemical{Name: "1-butanol"})
+	if ErrorKindOf(err) != ErrorKindTransport {
+		t.Fatalf("err %v, want a transport error", err)
+	}
+	if !strings.Contains(err.Error(), "after 3 attempts") {
+		t.Errorf("err %q does not say how often the create was tried", err)
+	}
+	if attempts := client.TakeAttempts(); attempts != 3 {
+		t.Errorf("%d attempts, want the refused create tried 3 times", attempts)
+	}
+	if attempts := client.TakeAttempts(); attempts != 0 {
+		t.Errorf("TakeAttempts did not reset, got %d", attempts)
+	}
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 10, BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	for attempt := 2; attempt <= 40; attempt++ {
+		limit := min(policy.BaseDelay<<min(attempt-2, 20), policy.MaxDelay)
+		if delay := policy.delay(attempt); delay < 0 || delay > limit {
+			t.Errorf("delay(%d) = %v, want between 0 and %v", attempt, delay, limit)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// casNumber matches the format of a CAS registry number: 2-7 digits, 2 digits and the check digit, e.g. 71-36-3
+var casNumber = regexp.MustCompile(`^(\d{2,7})-(\d{2})-(\d)$`)
+
+// casLike matches a cell that is meant to be one CAS number, even if it is malformed, e.g. "56797-01-04"
+var casLike = regexp.MustCompile(`^\d+(-\d+)+$`)
+
+// casDashes are the dashes a CAS number gets when pasted from a PDF or a website
+var casDashes = strings.NewReplacer("‐", "-", "‑", "-", "‒", "-", "–", "-", "—", "-", "−", "-")
+
+// ValidateCasNumber checks the format and the check digit of a CAS number.
+// The check digit is the sum of the other digits, weighted 1, 2, 3... from the right, modulo 10.
+func ValidateCasNumber(cas string) error {
+	match := casNumber.FindStringSubmatch(cas)
+	if match == nil {
+		return fmt.Errorf("%q is not a CAS number (expected e.g. 71-36-3)", cas)
+	}
+
+	digits := match[1] + match[2]
+	sum := 0
+	for i := range digits {
+		sum += int(digits[len(digits)-1-i]-'0') * (i + 1)
+	}
+	if want := sum % 10; int(match[3][0]-'0') != want {
+		return fmt.Errorf("CAS number %q has check digit %s, expected %d", cas, match[3], want)
+	}
+	return nil
+}
+
+// parseCasCell reads the CAS column of a row. A cell holding one valid CAS number returns it, and the chemical
+// is matched by it. A mixture ("contains 7440-22-4, 67-63-0") or a remark returns no CAS number and the text
+// as a note, and an invalid CAS number ("1394595-45-5") also returns an error.
+func parseCasCell(raw string) (cas string, note string, err error) {
+	text := strings.Join(strings.Fields(casDashes.Replace(raw)), " ")
+	if isNoData(text) {
+		return "", "", nil
+	}
+
+	if !casLike.MatchString(text) {
+		return "", "CAS: " + text, nil
+	}
+	if err := ValidateCasNumber(text); err != nil {
+		return "", "CAS: " + text, err
+	}
+	return text, "", nil
+}
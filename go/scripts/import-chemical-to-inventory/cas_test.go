@@ -0,0 +1,39 @@
+package main
+
+import "testing"
+
+func TestValidateCasNumber(t *testing.T) {
+	for _, cas := range []string{"71-36-3", "64-17-5", "7732-18-5", "7440-22-4", "90076-65-6", "1333-74-0"} {
+		if err := ValidateCasNumber(cas); err != nil {
+			t.Errorf("ValidateCasNumber(%q): %v", cas, err)
+		}
+	}
+	for _, cas := range []string{"71-36-4", "1394595-45-5", "56797-01-04", "7-36-3", "71363"} {
+		if err := ValidateCasNumber(cas); err == nil {
+			t.Errorf("ValidateCasNumber(%q): want an error", cas)
+		}
+	}
+}
+
+func TestParseCasCell(t *testing.T) {
+	tests := []struct {
+		raw     string
+		cas     string
+		note    string
+		wantErr bool
+	}{
+		{" 71-36-3 ", "71-36-3", "", false},
+		{"64–17–5", "64-17-5", "", false},
+		{"", "", "", false},
+		{"mixture", "", "", false},
+		{"contains 7440-22-4, 67-63-0", "", "CAS: contains 7440-22-4, 67-63-0", false},
+		{"64-17-5 (also contains 67-56-1\n, 67-63-0)", "", "CAS: 64-17-5 (also contains 67-56-1 , 67-63-0)", false},
+		{"1394595-45-5", "", "CAS: 1394595-45-5", true},
+	}
+	for _, tt := range tests {
+		cas, note, err := parseCasCell(tt.raw)
+		if cas != tt.cas || note != tt.note || (err != nil) != tt.wantErr {
+			t.Errorf("parseCasCell(%q) = %q, %q, %v; want %q, %q, error %v", tt.raw, cas, note, err, tt.cas, tt.note, tt.wantErr)
+		}
+	}
+}
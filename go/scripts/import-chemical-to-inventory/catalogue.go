@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/miru-smart-technologies/Scripts/go/pkg/portal"
+)
+
+// CatalogueClient wraps a PortalClient with an in-memory index of the chemicals and recipes of the Portal.
+// The index is loaded once before the rows are processed; the chemical and recipe checks are answered from it
+// instead of one lookup per row, and the creates add to it. The index matches like the Portal lookups do, and
+// a check it misses is passed on to the Portal, so a record the listing left out or someone else created during
+// the run is still found before the import creates it again.
+type CatalogueClient struct {
+	portal.PortalClient
+	index *catalogueIndex
+}
+
+// catalogueIndex is the index of a CatalogueClient, shared by the clients of all workers
+type catalogueIndex struct {
+	mu              sync.RWMutex
+	chemicalsByName map[string]catalogueChemical // NFC name -> chemical
+	chemicalsByCas  map[string]string            // NFC CAS number -> ID
+	recipes         map[string]string            // recipeKey -> ID
+}
+
+// catalogueChemical is a chemical of the index, with the CAS number the name lookup checks
+type catalogueChemical struct {
+	id  string
+	cas string
+}
+
+// LoadCatalogue fetches the chemical and recipe catalogue of the Portal behind client
+func LoadCatalogue(client portal.PortalClient) (*CatalogueClient, error) {
+	chemicals, err := client.ListChemicals()
+	if err != nil {
+		return nil, err
+	}
+	recipes, err := client.ListChemicalRecipes()
+	if err != nil {
+		return nil, err
+	}
+
+	c := &CatalogueClient{
+		PortalClient: client,
+		index: &catalogueIndex{
+			chemicalsByName: map[string]catalogueChemical{},
+			chemicalsByCas:  map[string]string{},
+			recipes:         map[string]string{},
+		},
+	}
+	for _, chemical := range chemicals {
+		c.index.addChemical(chemical.Name, chemical.SafetyInfo.CasNumber, chemical.ID)
+	}
+	for _, recipe := range recipes {
+		c.index.addRecipe(recipe.ChemicalUUID.String(), recipe.Title, recipe.ID)
+	}
+	return c, nil
+}
+
+// WithClient returns a CatalogueClient that shares the index of c but calls the Portal through client, for another worker
+func (c *CatalogueClient) WithClient(client portal.PortalClient) *CatalogueClient {
+	return &CatalogueClient{PortalClient: client, index: c.index}
+}
+
+// recipeKey identifies a recipe by its chemical and title; titles are compared in NFC, like the Portal check does
+func recipeKey(chemicalID string, title string) string {
+	return strings.ToLower(chemicalID) + "/" + portal.NormaliseText(title)
+}
+
+// addChemical indexes a chemical under its exact name and CAS number in NFC, which is what the Portal lookups
+// compare; the first chemical of a name or CAS number wins, like it does for them
+func (x *catalogueIndex) addChemical(name string, cas string, id string) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	if key := portal.NormaliseText(name); key != "" {
+		if _, ok := x.chemicalsByName[key]; !ok {
+			x.chemicalsByName[key] = catalogueChemical{id: id, cas: cas}
+		}
+	}
+	if key := portal.NormaliseText(cas); key != "" {
+		if _, ok := x.chemicalsByCas[key]; !ok {
+			x.chemicalsByCas[key] = id
+		}
+	}
+}
+
+func (x *catalogueIndex) addRecipe(chemicalID string, title string, id string) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	x.recipes[recipeKey(chemicalID, title)] = id
+}
+
+// lookup returns the ID of key in one of the maps of the index
+func (x *catalogueIndex) lookup(ids map[string]string, key string) (string, bool) {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	id, ok := ids[key]
+	return id, ok
+}
+
+// lookupChemical returns the chemical of name in the index
+func (x *catalogueIndex) lookupChemical(name string) (catalogueChemical, bool) {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	chemical, ok := x.chemicalsByName[portal.NormaliseText(name)]
+	return chemical, ok
+}
+
+// String describes the size of the catalogue for the console output
+func (c *CatalogueClient) String() string {
+	c.index.mu.RLock()
+	defer c.index.mu.RUnlock()
+
+	return fmt.Sprintf("%d chemicals (%d with a CAS number), %d recipes", len(c.index.chemicalsByName), len(c.index.chemicalsByCas), len(c.index.recipes))
+}
+
+func (c *CatalogueClient) CheckIfChemicalExists(name string) (bool, string, error) {
+	if chemical, ok := c.index.lookupChemical(name); ok {
+		return true, chemical.id, nil
+	}
+	return c.PortalClient.CheckIfChemicalExists(name)
+}
+
+func (c *CatalogueClient) CheckIfChemicalWithCasExists(cas string) (bool, string, error) {
+	if id, ok := c.index.lookup(c.index.chemicalsByCas, portal.NormaliseText(cas)); ok {
+		return true, id, nil
+	}
+	return c.PortalClient.CheckIfChemicalWithCasExists(cas)
+}
+
+// CheckIfChemicalWithNameAndCasExists finds the chemical of the name if it has no CAS number or the given one.
+// Like the Portal lookup it only looks at the first chemical of the name.
+func (c *CatalogueClient) CheckIfChemicalWithNameAndCasExists(name string, cas string) (bool, string, error) {
+	if chemical, ok := c.index.lookupChemical(name); ok {
+		if existing := strings.TrimSpace(chemical.cas); existing != "" && existing != strings.TrimSpace(cas) {
+			return false, "", nil
+		}
+		return true, chemical.id, nil
+	}
+	return c.PortalClient.CheckIfChemicalWithNameAndCasExists(name, cas)
+}
+
+func (c *CatalogueClient) CreateChemical(pChemical portal.PayloadChemical) (string, error) {
+	id, err := c.PortalClient.CreateChemical(pChemical)
+	if err == nil {
+		c.index.addChemical(pChemical.Name, pChemical.SafetyInfo.CasNumber, id)
+	}
+	return id, err
+}
+
+func (c *CatalogueClient) CheckIfChemicalRecipeExists(name string, chemicalID string) (bool, string, error) {
+	if id, ok := c.index.lookup(c.index.recipes, recipeKey(chemicalID, name)); ok {
+		return true, id, nil
+	}
+	return c.PortalClient.CheckIfChemicalRecipeExists(name, chemicalID)
+}
+
+func (c *CatalogueClient) CreateChemicalRecipe(pRecipe portal.PayloadChemicalRecipe) (string, error) {
+	id, err := c.PortalClient.CreateChemicalRecipe(pRecipe)
+	if err == nil {
+		c.index.addRecipe(pRecipe.ChemicalUUID.String(), pRecipe.Title, id)
+	}
+	return id, err
+}
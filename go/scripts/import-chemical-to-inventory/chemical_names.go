@@ -0,0 +1,293 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/miru-smart-technologies/Scripts/go/pkg/portal"
+)
+
+// chemicalDashes are the unicode dashes and hyphens that end up in names pasted from a PDF or a website
+var chemicalDashes = strings.NewReplacer("‐", "-", "‑", "-", "‒", "-", "–", "-", "—", "-", "―", "-", "−", "-")
+
+// chemicalSynonyms maps the key of a common synonym (see chemicalNameKey) to the key of the name it stands for.
+// Only names that are the same substance belong here - a grade or a mixture ("reagent alcohol") is not a synonym.
+var chemicalSynonyms = map[string]string{
+	"n-butyl alcohol":         "1-butanol",
+	"butan-1-ol":              "1-butanol",
+	"ethyl alcohol":           "ethanol",
+	"methyl alcohol":          "methanol",
+	"isopropanol":             "2-propanol",
+	"isopropyl alcohol":       "2-propanol",
+	"propan-2-ol":             "2-propanol",
+	"ipa":                     "2-propanol",
+	"dmso":                    "dimethyl sulfoxide",
+	"thf":                     "tetrahydrofuran",
+	"dmf":                     "n,n-dimethylformamide",
+	"dimethylformamide":       "n,n-dimethylformamide",
+	"nmp":                     "n-methyl-2-pyrrolidone",
+	"dmc":                     "dimethyl carbonate",
+	"acetic acid ethyl ester": "ethyl acetate",
+}
+
+var (
+	// normalAlcohol matches "n-butanol", which is 1-butanol - "n-hexane" is left alone
+	normalAlcohol = regexp.MustCompile(`^n-([a-z]+anol)$`)
+	// spaceAroundPunctuation matches the spaces of "N, N-dimethylaniline" and "zirconium (IV)"
+	spaceAroundPunctuation = regexp.MustCompile(`\s*([-,()\[\]])\s*`)
+)
+
+// normaliseChemicalName cleans up a chemical name cell: the text is brought into NFC, unicode dashes become "-"
+// and whitespace is collapsed. Other characters - "®", Greek letters, primes, commas, slashes - are kept as they are.
+func normaliseChemicalName(raw string) string {
+	return strings.Join(strings.Fields(chemicalDashes.Replace(portal.NormaliseText(raw))), " ")
+}
+
+// chemicalSpellingKey reduces a name to the spelling it shares with its case, dash and whitespace variants,
+// e.g. "1-Butanol", "1 - butanol " and "1‐butanol" all have the key "1-butanol"
+func chemicalSpellingKey(name string) string {
+	return spaceAroundPunctuation.ReplaceAllString(strings.ToLower(normaliseChemicalName(name)), "$1")
+}
+
+// chemicalNameKey is chemicalSpellingKey with the common synonyms resolved, so "n-butanol" has the key "1-butanol"
+func chemicalNameKey(name string) string {
+	key := chemicalSpellingKey(name)
+	if synonym, ok := chemicalSynonyms[key]; ok {
+		return synonym
+	}
+	return normalAlcohol.ReplaceAllString(key, "1-$1")
+}
+
+// ChemicalNames resolves the chemical name of a row to the name used in the Portal.
+// Spelling variants of a name are merged into the variant the sheet uses most; other likely duplicates
+// (synonyms) are only merged through the chemical alias file, after someone has reviewed them.
+type ChemicalNames struct {
+	names  map[string]string // spelling key -> name to use
+	groups []DuplicateGroup  // names that are, or may be, the same chemical
+}
+
+// DuplicateGroup is a set of chemical names of the sheet with the same chemicalNameKey
+type DuplicateGroup struct {
+	Key      string
+	Variants []NameVariant
+}
+
+// NameVariant is one spelling of a chemical name and the rows that use it
+type NameVariant struct {
+	Name       string
+	Resolved   string   // the name the import uses for it
+	Rows       []int    // FileRowNum of the rows
+	CasNumbers []string // distinct CAS cells of the rows
+}
+
+// NeedsReview reports whether the group still holds more than one chemical after the merges
+func (g DuplicateGroup) NeedsReview() bool {
+	for _, variant := range g.Variants[1:] {
+		if variant.Resolved != g.Variants[0].Resolved {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadChemicalAliases reads the chemical alias file, an "alias,chemical" CSV, into spelling key -> name
+func LoadChemicalAliases(filename string) (map[string]string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("error opening chemical alias file: %w", err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.Comment = '#'
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("error reading chemical alias file: %w", err)
+	}
+
+	aliases := map[string]string{}
+	for i, record := range records {
+		if i == 0 {
+			continue // Skip header line
+		}
+		if len(record) != 2 {
+			return nil, fmt.Errorf("line %d of chemical alias file: expected 2 fields, got %d", i+1, len(record))
+		}
+
+		alias := chemicalSpellingKey(record[0])
+		name := normaliseChemicalName(record[1])
+		if alias == "" || name == "" {
+			return nil, fmt.Errorf("line %d of chemical alias file: alias and chemical are required", i+1)
+		}
+		if existing, ok := aliases[alias]; ok && existing != name {
+			return nil, fmt.Errorf("line %d of chemical alias file: alias %q already maps to %q", i+1, record[0], existing)
+		}
+		aliases[alias] = name
+	}
+
+	return aliases, nil
+}
+
+// ResolveChemicalNames reads the chemical names of the CSV before anything is created and works out
+// the name to use for each, applying the reviewed aliases first
+func ResolveChemicalNames(csvFilename string, cols *Columns, aliases map[string]string) (*ChemicalNames, error) {
+	rows, err := readCsvRows(csvFilename)
+	if err != nil {
+		return nil, err
+	}
+
+	// variants by spelling key, then by spelling, in the order of the sheet
+	type spelling struct {
+		variants map[string]*NameVariant
+		order    []string
+	}
+	spellings := map[string]*spelling{}
+	var keys []string
+	for _, r := range rows {
+		name := normaliseChemicalName(cols.GetOptionalValueFromRow(r.row, cols.ChemicalName, ""))
+		if name == "" {
+			continue
+		}
+
+		key := chemicalSpellingKey(name)
+		s, ok := spellings[key]
+		if !ok {
+			s = &spelling{variants: map[string]*NameVariant{}}
+			spellings[key] = s
+			keys = append(keys, key)
+		}
+		variant, ok := s.variants[name]
+		if !ok {
+			variant = &NameVariant{Name: name}
+			s.variants[name] = variant
+			s.order = append(s.order, name)
+		}
+		variant.Rows = append(variant.Rows, r.rowNum)
+		if cas := strings.Join(strings.Fields(cols.GetOptionalValueFromRow(r.row, cols.CasNumber, "")), " "); cas != "" && !containsString(variant.CasNumbers, cas) {
+			variant.CasNumbers = append(variant.CasNumbers, cas)
+		}
+	}
+
+	cn := &ChemicalNames{names: map[string]string{}}
+	groups := map[string]*DuplicateGroup{}
+	var groupKeys []string
+	for _, key := range keys {
+		s := spellings[key]
+
+		// the alias file wins, then the spelling most rows use - the first one on a tie
+		resolved := aliases[key]
+		if resolved == "" {
+			for _, name := range s.order {
+				if resolved == "" || len(s.variants[name].Rows) > len(s.variants[resolved].Rows) {
+					resolved = name
+				}
+			}
+		}
+		cn.names[key] = resolved
+
+		groupKey := chemicalNameKey(resolved)
+		group, ok := groups[groupKey]
+		if !ok {
+			group = &DuplicateGroup{Key: groupKey}
+			groups[groupKey] = group
+			groupKeys = append(groupKeys, groupKey)
+		}
+		for _, name := range s.order {
+			variant := s.variants[name]
+			variant.Resolved = resolved
+			group.Variants = append(group.Variants, *variant)
+		}
+	}
+
+	for _, key := range groupKeys {
+		if len(groups[key].Variants) > 1 {
+			cn.groups = append(cn.groups, *groups[key])
+		}
+	}
+	return cn, nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Resolve returns the name to use in the Portal for a chemical name cell
+func (cn *ChemicalNames) Resolve(raw string) string {
+	name := normaliseChemicalName(raw)
+	if resolved, ok := cn.names[chemicalSpellingKey(name)]; ok {
+		return resolved
+	}
+	return name
+}
+
+// Groups returns the names of the sheet that are, or may be, the same chemical
+func (cn *ChemicalNames) Groups() []DuplicateGroup {
+	return cn.groups
+}
+
+// ReviewCount returns the number of groups that still hold more than one chemical
+func (cn *ChemicalNames) ReviewCount() int {
+	count := 0
+	for _, group := range cn.groups {
+		if group.NeedsReview() {
+			count++
+		}
+	}
+	return count
+}
+
+// WriteDuplicateReport writes the duplicate groups to a CSV for review, one line per spelling
+func WriteDuplicateReport(filename string, groups []DuplicateGroup) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("failed to create duplicate report: %w", err)
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	writer.Write([]string{"Group", "Name", "UsedName", "Rows", "CasNumbers", "Decision"})
+	for i, group := range groups {
+		decision := "merged"
+		if group.NeedsReview() {
+			decision = "review - add an alias to merge"
+		}
+		for _, variant := range group.Variants {
+			rows := make([]string, len(variant.Rows))
+			for j, row := range variant.Rows {
+				rows[j] = strconv.Itoa(row)
+			}
+			writer.Write([]string{
+				strconv.Itoa(i + 1),
+				variant.Name,
+				variant.Resolved,
+				strings.Join(rows, " "),
+				strings.Join(variant.CasNumbers, "; "),
+				decision,
+			})
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// sortedGroupNames returns the distinct names the import uses for a group, for the console output
+func sortedGroupNames(group DuplicateGroup) []string {
+	var names []string
+	for _, variant := range group.Variants {
+		if !containsString(names, variant.Resolved) {
+			names = append(names, variant.Resolved)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
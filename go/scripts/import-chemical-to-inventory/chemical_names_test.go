@@ -0,0 +1,120 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestChemicalNameKey(t *testing.T) {
+	tests := map[string]string{
+		"1-Butanol":             "1-butanol",
+		" 1 - butanol ":         "1-butanol",
+		"1‐butanol":             "1-butanol",
+		"n-butanol":             "1-butanol",
+		"n-Butyl alcohol":       "1-butanol",
+		"Isopropyl alcohol":     "2-propanol",
+		"n-hexane":              "n-hexane",
+		"N, N-dimethylaniline":  "n,n-dimethylaniline",
+		"nickel (II) carbonate": "nickel(ii)carbonate",
+		"nickel(II)  carbonate": "nickel(ii)carbonate",
+	}
+	for name, want := range tests {
+		if got := chemicalNameKey(name); got != want {
+			t.Errorf("chemicalNameKey(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestResolveChemicalNames(t *testing.T) {
+	fake := newFakePortal(t)
+	opts := testOptions(t, fake, writeTestCsv(t, "chemicals-05-20-16-55.csv", []map[string]string{
+		{"Chemical Name": "1-butanol"},
+		{"Chemical Name": "1-Butanol "},
+		{"Chemical Name": "1‐butanol"},
+		{"Chemical Name": "1-Butanol"},
+		{"Chemical Name": "1-Butanol"},
+		{"Chemical Name": "n-butanol"},
+		{"Chemical Name": "ethanol"},
+	}))
+	cols := testColumns(t, opts)
+	if _, err := ApplySheetLayout(cols, opts.LayoutsDir); err != nil {
+		t.Fatal(err)
+	}
+
+	names, err := ResolveChemicalNames(cols.RawCsv, cols, map[string]string{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the spelling most rows use wins
+	for _, raw := range []string{"1-butanol", "1‐butanol", " 1-BUTANOL"} {
+		if got := names.Resolve(raw); got != "1-Butanol" {
+			t.Errorf("Resolve(%q) = %q, want 1-Butanol", raw, got)
+		}
+	}
+	if got := names.Resolve("n-butanol"); got != "n-butanol" {
+		t.Errorf("Resolve(n-butanol) = %q, a synonym must not be merged before it is reviewed", got)
+	}
+	if len(names.Groups()) != 1 || names.ReviewCount() != 1 {
+		t.Fatalf("got %d groups, %d to review; want the butanols in one group to review", len(names.Groups()), names.ReviewCount())
+	}
+
+	report := filepath.Join(t.TempDir(), "duplicates.csv")
+	if err := WriteDuplicateReport(report, names.Groups()); err != nil {
+		t.Fatal(err)
+	}
+	// "1‐butanol" and "1-Butanol " are the same spellings as "1-butanol" and "1-Butanol" once cleaned up
+	if records := readCsv(t, report); len(records) != 4 {
+		t.Errorf("report has %d lines, want a header and one line per spelling", len(records))
+	}
+
+	// once reviewed, the alias merges the synonym
+	names, err = ResolveChemicalNames(cols.RawCsv, cols, map[string]string{"n-butanol": "1-Butanol"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := names.Resolve("n-butanol"); got != "1-Butanol" || names.ReviewCount() != 0 {
+		t.Errorf("Resolve(n-butanol) = %q with %d groups to review, want the alias applied", got, names.ReviewCount())
+	}
+}
+
+func TestResolveChemicalNamesSkipsUnreadableRows(t *testing.T) {
+	fake := newFakePortal(t)
+	csvFile := writeTestCsv(t, "chemicals-05-20-16-55.csv", []map[string]string{
+		{"Chemical Name": "1-Butanol"},
+		{"Chemical Name": "short row"},
+		{"Chemical Name": "Ethanol"},
+	})
+	// cut a row short, like one a spreadsheet exported with fewer columns
+	data, err := os.ReadFile(csvFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(string(data), "\n")
+	for i, line := range lines {
+		if strings.Contains(line, "short row") {
+			lines[i] = ",,short row"
+		}
+	}
+	if err := os.WriteFile(csvFile, []byte(strings.Join(lines, "\n")), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := testOptions(t, fake, csvFile)
+	cols := testColumns(t, opts)
+	if _, err := ApplySheetLayout(cols, opts.LayoutsDir); err != nil {
+		t.Fatal(err)
+	}
+
+	names, err := ResolveChemicalNames(cols.RawCsv, cols, map[string]string{})
+	if err != nil {
+		t.Fatalf("ResolveChemicalNames: %v, want the short row skipped", err)
+	}
+	// the spellings of the sheet are found before and after the short row
+	for raw, want := range map[string]string{"1-butanol": "1-Butanol", "ethanol": "Ethanol"} {
+		if got := names.Resolve(raw); got != want {
+			t.Errorf("Resolve(%q) = %q, want %q", raw, got, want)
+		}
+	}
+}
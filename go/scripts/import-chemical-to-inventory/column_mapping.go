@@ -1,7 +1,9 @@
 package main
 
 import (
+	"encoding/csv"
 	"fmt"
+	"io"
 	"os"
 	"strings"
 
@@ -15,7 +17,15 @@ type Columns struct {
 	CasNumber                  int
 	UnNumber                   int
 	HazardClass                int
+	PackingGroup               int
 	GhsFlammableLiquidCategory int
+	GhsHazards                 []int // one per ghsHazardClasses entry
+
+	// Physical property columns
+	StateOfMatter   int
+	Colour          int
+	MolecularWeight int
+	Density         int
 
 	// Recipe columns
 	RecipeTitle int
@@ -26,17 +36,81 @@ type Columns struct {
 	// Location columns
 	LocationName int
 
+	// Owner columns
+	Owner int
+
 	// Instance columns
-	Ciid           int
-	LotNumber      int
-	Amount         int
-	ExpirationDate int
-	ParentID       int
-	Label          int
+	Ciid            int
+	LotNumber       int
+	Amount          int // free text, e.g. "2 x 250 g"
+	AmountKg        int // total amount in AmountUnit, in newer exports
+	AmountUnit      int
+	ExpirationDate  int
+	ManufactureDate int
+	ReceivedDate    int // counts shelf lives like "12 month shelf life" in the expiry column
+	ParentID        int
+	Label           int
 
 	// API configuration
 	ApiBaseUrl string
 	RawCsv     string
+
+	// Owner configuration
+	DefaultOwnerUUID string
+
+	// env holds the raw values of the mapping file
+	env map[string]string
+	// headerNames holds the mappings given as header text, resolved by ResolveHeaders
+	headerNames map[string]string
+}
+
+// columnField is a column mapping key of the mapping file and the index it sets
+type columnField struct {
+	key   string
+	index *int
+}
+
+// ghsHazardColumns returns the unmapped columns of all GHS hazard classes
+func ghsHazardColumns() []int {
+	columns := make([]int, len(ghsHazardClasses))
+	for i := range columns {
+		columns[i] = -1
+	}
+	return columns
+}
+
+// columnFields lists the column mapping keys in mapping file order, the GHS hazard classes last
+func (c *Columns) columnFields() []columnField {
+	fields := []columnField{
+		{"COLUMN_CHEMICAL_NAME", &c.ChemicalName},
+		{"COLUMN_CAS_NUMBER", &c.CasNumber},
+		{"COLUMN_UN_NUMBER", &c.UnNumber},
+		{"COLUMN_HAZARD_CLASS", &c.HazardClass},
+		{"COLUMN_PACKING_GROUP", &c.PackingGroup},
+		{"COLUMN_GHS_FLAMMABLE_LIQUID_CATEGORY", &c.GhsFlammableLiquidCategory},
+		{"COLUMN_STATE_OF_MATTER", &c.StateOfMatter},
+		{"COLUMN_COLOUR", &c.Colour},
+		{"COLUMN_MOLECULAR_WEIGHT", &c.MolecularWeight},
+		{"COLUMN_DENSITY", &c.Density},
+		{"COLUMN_RECIPE_TITLE", &c.RecipeTitle},
+		{"COLUMN_SUPPLIER_NAME", &c.SupplierName},
+		{"COLUMN_LOCATION_NAME", &c.LocationName},
+		{"COLUMN_OWNER", &c.Owner},
+		{"COLUMN_CIID", &c.Ciid},
+		{"COLUMN_LOT_NUMBER", &c.LotNumber},
+		{"COLUMN_AMOUNT", &c.Amount},
+		{"COLUMN_AMOUNT_KG", &c.AmountKg},
+		{"COLUMN_AMOUNT_UNIT", &c.AmountUnit},
+		{"COLUMN_EXPIRATION_DATE", &c.ExpirationDate},
+		{"COLUMN_MANUFACTURE_DATE", &c.ManufactureDate},
+		{"COLUMN_RECEIVED_DATE", &c.ReceivedDate},
+		{"COLUMN_PARENT_ID", &c.ParentID},
+		{"COLUMN_LABEL", &c.Label},
+	}
+	for i, class := range ghsHazardClasses {
+		fields = append(fields, columnField{class.key, &c.GhsHazards[i]})
+	}
+	return fields
 }
 
 // NewColumns creates a new Columns structure with all indices initialized to -1
@@ -46,16 +120,28 @@ func NewColumns() *Columns {
 		CasNumber:                  -1,
 		UnNumber:                   -1,
 		HazardClass:                -1,
+		PackingGroup:               -1,
 		GhsFlammableLiquidCategory: -1,
 		RecipeTitle:                -1,
 		SupplierName:               -1,
 		LocationName:               -1,
+		Owner:                      -1,
 		Ciid:                       -1,
 		LotNumber:                  -1,
 		Amount:                     -1,
+		AmountKg:                   -1,
+		AmountUnit:                 -1,
 		ExpirationDate:             -1,
+		ManufactureDate:            -1,
+		ReceivedDate:               -1,
 		ParentID:                   -1,
 		Label:                      -1,
+		StateOfMatter:              -1,
+		Colour:                     -1,
+		MolecularWeight:            -1,
+		Density:                    -1,
+		GhsHazards:                 ghsHazardColumns(),
+		headerNames:                map[string]string{},
 	}
 }
 
@@ -70,39 +156,190 @@ func LetterToIndex(colLetter string) int {
 	return result - 1 // Convert to 0-based index
 }
 
-// LoadFromEnv loads column mappings from environment variables
+// IndexToLetter converts a zero-based index back to its Excel-style column letter
+func IndexToLetter(index int) string {
+	letter := ""
+	for n := index + 1; n > 0; n = (n - 1) / 26 {
+		letter = string(rune('A'+(n-1)%26)) + letter
+	}
+	return letter
+}
+
+// isColumnLetter reports whether a mapping value is a column letter (A to ZZZ) rather than header text.
+// Letters must be upper case, so a header such as "Class" or "Recipe" is matched by name.
+func isColumnLetter(value string) bool {
+	if len(value) == 0 || len(value) > 3 {
+		return false
+	}
+	for _, char := range value {
+		if char < 'A' || char > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+// normaliseHeader lowercases a header and collapses all whitespace (including newlines) to single spaces
+func normaliseHeader(s string) string {
+	return strings.Join(strings.Fields(strings.ToLower(s)), " ")
+}
+
+// LoadFromEnv loads column mappings from the mapping env file. A mapping is a column letter (C) or header text (Chemical Name).
+// The file is read without touching the process environment, so several mapping files can be loaded in one process.
 func (c *Columns) LoadFromEnv(filename string) error {
-	if err := godotenv.Load(filename); err != nil {
+	env, err := godotenv.Read(filename)
+	if err != nil {
 		return fmt.Errorf("error loading .env file: %w", err)
 	}
+	c.env = env
+
+	for _, field := range c.columnFields() {
+		c.setMapping(field, env[field.key])
+	}
+
+	c.RawCsv = env["RAW_CSV"]
+	c.DefaultOwnerUUID = env["DEFAULT_OWNER_UUID"]
+
+	return nil
+}
+
+// setMapping applies one mapping value. A column letter sets the index right away,
+// header text is resolved by ResolveHeaders once the header row is read.
+func (c *Columns) setMapping(field columnField, value string) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return
+	}
+	if isColumnLetter(value) {
+		*field.index = LetterToIndex(value)
+		delete(c.headerNames, field.key)
+	} else {
+		c.headerNames[field.key] = value
+	}
+}
+
+// mappingValue returns the mapping of a field and where it comes from: the mapping file, which takes precedence, or the layout
+func (c *Columns) mappingValue(key string, layout *Layout) (value string, source string) {
+	if value := strings.TrimSpace(c.env[key]); value != "" {
+		return value, "mapping file"
+	}
+	if layout != nil {
+		if value := strings.TrimSpace(layout.Mappings[key]); value != "" {
+			return value, "layout " + layout.Name
+		}
+	}
+	return "", ""
+}
+
+// ApplyLayout applies the column mappings of a sheet layout. Mappings in the mapping file take precedence.
+func (c *Columns) ApplyLayout(layout *Layout) {
+	for _, field := range c.columnFields() {
+		value, source := c.mappingValue(field.key, layout)
+		if source == "mapping file" {
+			if _, ok := layout.Mappings[field.key]; ok {
+				fmt.Printf("%s of layout %s is overridden by the mapping file\n", field.key, layout.Name)
+			}
+			continue
+		}
+		c.setMapping(field, value)
+	}
+}
+
+// findHeader returns the index of the one column of the header row with the given header text
+func findHeader(header []string, headerName string) (int, error) {
+	var matches []int
+	for i, text := range header {
+		if normaliseHeader(text) == normaliseHeader(headerName) {
+			matches = append(matches, i)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return -1, fmt.Errorf("no column has the header %q", headerName)
+	case 1:
+		return matches[0], nil
+	}
+
+	var letters []string
+	for _, i := range matches {
+		letters = append(letters, IndexToLetter(i))
+	}
+	return -1, fmt.Errorf("header %q is ambiguous, it is used by columns %s - map it by letter instead",
+		headerName, strings.Join(letters, ", "))
+}
+
+// ResolveHeaders sets the columns mapped by header text from the header row of the CSV.
+// Headers match ignoring case and whitespace; a mapped header that is missing or appears more than once is an error.
+func (c *Columns) ResolveHeaders(header []string) error {
+	var problems []string
+	for _, field := range c.columnFields() {
+		headerName, ok := c.headerNames[field.key]
+		if !ok {
+			continue
+		}
 
-	envMappings := map[string]*int{
-		"COLUMN_CHEMICAL_NAME":                 &c.ChemicalName,
-		"COLUMN_CAS_NUMBER":                    &c.CasNumber,
-		"COLUMN_UN_NUMBER":                     &c.UnNumber,
-		"COLUMN_HAZARD_CLASS":                  &c.HazardClass,
-		"COLUMN_GHS_FLAMMABLE_LIQUID_CATEGORY": &c.GhsFlammableLiquidCategory,
-		"COLUMN_RECIPE_TITLE":                  &c.RecipeTitle,
-		"COLUMN_SUPPLIER_NAME":                 &c.SupplierName,
-		"COLUMN_LOCATION_NAME":                 &c.LocationName,
-		"COLUMN_CIID":                          &c.Ciid,
-		"COLUMN_LOT_NUMBER":                    &c.LotNumber,
-		"COLUMN_AMOUNT":                        &c.Amount,
-		"COLUMN_EXPIRATION_DATE":               &c.ExpirationDate,
-		"COLUMN_PARENT_ID":                     &c.ParentID,
-		"COLUMN_LABEL":                         &c.Label,
-	}
-
-	for envName, columnPtr := range envMappings {
-		colLetter := os.Getenv(envName)
-		if colLetter != "" {
-			*columnPtr = LetterToIndex(colLetter)
+		index, err := findHeader(header, headerName)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", field.key, err))
+			continue
 		}
+		*field.index = index
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("column mapping does not match the CSV header:\n\t%s", strings.Join(problems, "\n\t"))
 	}
 
 	return nil
 }
 
+// readCsvHeader reads the header row of a CSV file
+func readCsvHeader(filename string) ([]string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	header, err := csv.NewReader(file).Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+
+	return header, nil
+}
+
+// readCsvRows reads the data rows of a CSV file with their row numbers, counted like the import counts them.
+// Rows that can't be read, e.g. with a different number of fields, are left out - the import logs and skips them.
+func readCsvRows(filename string) ([]rowJob, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	if _, err := reader.Read(); err == io.EOF {
+		return nil, fmt.Errorf("%s is empty - expected a header row", filename)
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+
+	var rows []rowJob
+	for rowNum := 1; ; rowNum++ {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			continue
+		}
+		rows = append(rows, rowJob{rowNum: rowNum, row: row})
+	}
+	return rows, nil
+}
+
 func (c *Columns) HasColumn(columnIndex int) bool {
 	return columnIndex >= 0
 }
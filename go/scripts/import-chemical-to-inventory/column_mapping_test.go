@@ -0,0 +1,87 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIndexToLetter(t *testing.T) {
+	for _, letter := range []string{"A", "Z", "AA", "AZ", "BA", "ZZ", "AAA"} {
+		if got := IndexToLetter(LetterToIndex(letter)); got != letter {
+			t.Errorf("IndexToLetter(LetterToIndex(%q)) = %q", letter, got)
+		}
+	}
+}
+
+func loadTestMapping(t *testing.T, mapping string) *Columns {
+	t.Helper()
+
+	filename := filepath.Join(t.TempDir(), "mapping.env")
+	if err := os.WriteFile(filename, []byte(mapping), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cols := NewColumns()
+	if err := cols.LoadFromEnv(filename); err != nil {
+		t.Fatal(err)
+	}
+	return cols
+}
+
+func TestResolveHeaders(t *testing.T) {
+	header := []string{"Row number", "CIID", "Chemical Name", "CAS Number ", "amount", "amount", " location", "est amount remaining \n(June 30, 2023)", "Class"}
+
+	cols := loadTestMapping(t, `
+COLUMN_CIID = B
+COLUMN_CHEMICAL_NAME = "chemical  name"
+COLUMN_CAS_NUMBER = CAS Number
+COLUMN_LOCATION_NAME = location
+COLUMN_HAZARD_CLASS = Class
+COLUMN_LABEL = "EST AMOUNT REMAINING (June 30, 2023)"
+`)
+	if err := cols.ResolveHeaders(header); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]int{
+		"CIID":          1,
+		"chemical name": 2,
+		"CAS number":    3,
+		"location":      6,
+		"class":         8,
+		"label":         7,
+	}
+	got := map[string]int{
+		"CIID":          cols.Ciid,
+		"chemical name": cols.ChemicalName,
+		"CAS number":    cols.CasNumber,
+		"location":      cols.LocationName,
+		"class":         cols.HazardClass,
+		"label":         cols.Label,
+	}
+	for field, index := range want {
+		if got[field] != index {
+			t.Errorf("%s column = %d, want %d", field, got[field], index)
+		}
+	}
+}
+
+func TestResolveHeadersMissingOrAmbiguous(t *testing.T) {
+	header := []string{"Row number", "CIID", "Chemical Name", "amount", "amount"}
+
+	cols := loadTestMapping(t, `
+COLUMN_CHEMICAL_NAME = "Chemical"
+COLUMN_AMOUNT = amount
+`)
+	err := cols.ResolveHeaders(header)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, want := range []string{`COLUMN_CHEMICAL_NAME: no column has the header "Chemical"`, "columns D, E"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %q", err, want)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/miru-smart-technologies/Scripts/go/pkg/portal"
+)
+
+const (
+	StageTest       = "test"
+	StageProduction = "production"
+
+	// NotFoundAuto asks the Portal how it reports a missing record before the run, see portal.ProbeNotFoundMode
+	NotFoundAuto = "auto"
+)
+
+// Options holds the command-line flags of a run
+type Options struct {
+	CsvFile          string        // input CSV, overrides RAW_CSV in the mapping file
+	MappingFile      string        // mapping env file
+	LocationAliases  string        // location alias CSV
+	ChemicalAliases  string        // chemical alias CSV
+	LayoutsDir       string        // directory of the known sheet layouts
+	ApiBaseUrl       string        // Portal API base URL, overrides API_BASE_URL_<STAGE> in the mapping file
+	LogFile          string        // processed log path
+	Stage            string        // test or production
+	DefaultOwnerUUID string        // overrides DEFAULT_OWNER_UUID in the mapping file
+	DryRun           bool          // only read from the Portal and write a plan instead of the processed log
+	ResumeFrom       string        // processed log of an earlier run to resume
+	MaxAttempts      int           // attempts per Portal call, see portal.RetryPolicy
+	Timeout          time.Duration // per Portal call attempt
+	Preload          bool          // check chemicals and recipes against a catalogue fetched once instead of per row
+	Workers          int           // rows processed at the same time
+	NotFound         string        // how the Portal reports a missing record: auto, strict or legacy
+	VerifyLookups    bool          // look every created chemical up by name again to check that a later run finds it
+}
+
+// ParseFlags parses the command-line flags into Options
+func ParseFlags(args []string) (*Options, error) {
+	opts := &Options{}
+
+	fs := flag.NewFlagSet("import-chemicals-to-inventory", flag.ContinueOnError)
+	fs.StringVar(&opts.CsvFile, "csv", "", "input CSV exported from the inventory sheet (default: RAW_CSV from the mapping file)")
+	fs.StringVar(&opts.MappingFile, "mapping", "chemical_inventory.env", "mapping env file: API base URLs, input CSV, default owner and column mapping overrides")
+	fs.StringVar(&opts.LocationAliases, "locations", "location_aliases.csv", "location alias CSV")
+	fs.StringVar(&opts.ChemicalAliases, "chemicals", "chemical_aliases.csv", "chemical alias CSV: reviewed names to merge into one chemical")
+	fs.StringVar(&opts.LayoutsDir, "layouts", "layouts", "directory of the known sheet layouts (*.env)")
+	fs.StringVar(&opts.ApiBaseUrl, "api", "", "Portal API base URL (default: API_BASE_URL_TEST or API_BASE_URL_PRODUCTION from the mapping file)")
+	fs.StringVar(&opts.LogFile, "log", "", "processed log output path (default: log-YYYY-MM-DD-HH-MM.csv, or plan-YYYY-MM-DD-HH-MM.csv for a dry run)")
+	fs.StringVar(&opts.Stage, "stage", StageTest, "target stage: test or production")
+	fs.BoolVar(&opts.DryRun, "dry-run", false, "validate and plan without creating anything in the Portal")
+	fs.StringVar(&opts.ResumeFrom, "resume", "", "processed log (log-*.csv) of an interrupted run - steps that succeeded there are not repeated")
+	fs.IntVar(&opts.MaxAttempts, "attempts", portal.DefaultRetryPolicy.MaxAttempts, "attempts per Portal call before a row step fails; 1 to not retry")
+	fs.DurationVar(&opts.Timeout, "timeout", 30*time.Second, "timeout of one attempt of a Portal call")
+	fs.BoolVar(&opts.Preload, "preload", true, "fetch the chemicals and recipes of the Portal once instead of looking them up row by row")
+	fs.IntVar(&opts.Workers, "workers", 1, "rows to process at the same time; rows of the same chemical, supplier or location still wait for each other")
+	fs.StringVar(&opts.NotFound, "not-found", NotFoundAuto, "how the Portal reports a missing record: strict (404), legacy (500) or auto to ask it before the run")
+	fs.BoolVar(&opts.VerifyLookups, "verify-lookups", true, "look every created chemical up by its name again and warn if the Portal doesn't find it")
+	fs.StringVar(&opts.DefaultOwnerUUID, "owner", "", "default owner UUID for created instances (overrides DEFAULT_OWNER_UUID in the mapping file)")
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if opts.Stage != StageTest && opts.Stage != StageProduction {
+		return nil, fmt.Errorf("unknown stage %q - expected %s or %s", opts.Stage, StageTest, StageProduction)
+	}
+
+	if opts.MaxAttempts < 1 {
+		return nil, fmt.Errorf("-attempts must be at least 1")
+	}
+
+	if opts.NotFound != NotFoundAuto {
+		if _, err := portal.ParseNotFoundMode(opts.NotFound); err != nil {
+			return nil, fmt.Errorf("unknown -not-found %q - expected %s, strict or legacy", opts.NotFound, NotFoundAuto)
+		}
+	}
+
+	if opts.Workers < 1 {
+		return nil, fmt.Errorf("-workers must be at least 1")
+	}
+
+	if opts.LogFile == "" {
+		prefix := "log-"
+		if opts.DryRun {
+			prefix = "plan-"
+		}
+		opts.LogFile = prefix + time.Now().Format("2006-01-02-15-04") + ".csv"
+	}
+
+	if opts.ResumeFrom != "" && opts.ResumeFrom == opts.LogFile {
+		return nil, fmt.Errorf("the new log %s would overwrite the log being resumed - pass a different -log", opts.LogFile)
+	}
+
+	return opts, nil
+}
+
+// stageApiBaseUrl returns the API base URL of a stage from the mapping file values in env, unless override is set
+func stageApiBaseUrl(env map[string]string, stage string, override string) (string, error) {
+	if override != "" {
+		return override, nil
+	}
+
+	baseUrl := env["API_BASE_URL_"+strings.ToUpper(stage)]
+	if baseUrl == "" {
+		return "", fmt.Errorf("no API base URL for stage %s - set API_BASE_URL_%s in the mapping file or use -api",
+			stage, strings.ToUpper(stage))
+	}
+
+	return baseUrl, nil
+}
+
+// ApplyOptions fills the API configuration from the mapping file and lets the flags override it.
+// Must be called after LoadFromEnv.
+func (c *Columns) ApplyOptions(opts *Options) error {
+	baseUrl, err := stageApiBaseUrl(c.env, opts.Stage, opts.ApiBaseUrl)
+	if err != nil {
+		return err
+	}
+	c.ApiBaseUrl = baseUrl
+
+	if opts.CsvFile != "" {
+		c.RawCsv = opts.CsvFile
+	}
+	if c.RawCsv == "" {
+		return fmt.Errorf("no input CSV - set RAW_CSV in the mapping file or use -csv")
+	}
+
+	if opts.DefaultOwnerUUID != "" {
+		c.DefaultOwnerUUID = opts.DefaultOwnerUUID
+	}
+
+	return nil
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// isoDate is the format the Portal takes for instance dates
+const isoDate = "2006-01-02"
+
+// dayLayouts and monthLayouts are the date formats of the sheet, after normaliseDateText
+var (
+	dayLayouts   = []string{"Jan 2 2006", "January 2 2006", "2 Jan 2006", "2 January 2006", "2006/01/02", "2006-01-02"}
+	monthLayouts = []string{"Jan 2006", "January 2006", "01/06", "01/2006"}
+)
+
+var (
+	// sept matches the "Sept" spelling, which time.Parse does not know
+	sept = regexp.MustCompile(`\bsept\b`)
+	// zeroForO matches a month typed with a zero, e.g. "0ct 9, 2024"
+	zeroForO = regexp.MustCompile(`^0(ct|ctober)\b`)
+	// partPrefix matches the part of a kit a date is given for, e.g. "Part A = Nov 2021"
+	partPrefix = regexp.MustCompile(`^part [a-z0-9]+\s*[=:]\s*`)
+	// shelfLife matches "12 month shelf life" and "2 year shelf life (Nov 2023)"
+	shelfLife = regexp.MustCompile(`^(\d+)\s*(month|year)s?\s+shelf\s*life\s*(?:\(([^)]*)\))?$`)
+	// numericDate matches a date with the day and month in an unknown order, e.g. "11/07/2026"
+	numericDate = regexp.MustCompile(`^(\d{1,2})/(\d{1,2})/(\d{4})$`)
+)
+
+// normaliseDateText lowercases a date cell and irons out the sheet's spelling, e.g. "Sept 13, 2024" -> "sep 13 2024"
+func normaliseDateText(raw string) string {
+	text := strings.ToLower(strings.Join(strings.Fields(raw), " "))
+	text = strings.ReplaceAll(text, ",", "")
+	text = sept.ReplaceAllString(text, "sep")
+	text = zeroForO.ReplaceAllString(text, "o$1")
+	return strings.TrimSpace(text)
+}
+
+// parseDate reads one date of the sheet. A month without a day ("March 2021", "09/24") is the last day
+// of the month when endOfMonth is set, as for expiry dates, and the first day otherwise.
+func parseDate(raw string, endOfMonth bool) (time.Time, error) {
+	text := normaliseDateText(raw)
+	text = partPrefix.ReplaceAllString(text, "")
+	// a remark after the date, e.g. "Oct 21 2021(received after this)"
+	text = strings.TrimSpace(parenthesised.ReplaceAllString(text, ""))
+
+	for _, layout := range dayLayouts {
+		if date, err := time.Parse(layout, text); err == nil {
+			return date, nil
+		}
+	}
+	for _, layout := range monthLayouts {
+		if date, err := time.Parse(layout, text); err == nil {
+			if endOfMonth {
+				date = date.AddDate(0, 1, -1)
+			}
+			return date, nil
+		}
+	}
+
+	// "11/07/2026" can only be read when one of the numbers can't be a month
+	if match := numericDate.FindStringSubmatch(text); match != nil {
+		first, _ := strconv.Atoi(match[1])
+		second, _ := strconv.Atoi(match[2])
+		year, _ := strconv.Atoi(match[3])
+		day, month := first, second
+		switch {
+		case first > 12 && second <= 12:
+		case second > 12 && first <= 12:
+			day, month = second, first
+		case first == second && first <= 12:
+		default:
+			return time.Time{}, fmt.Errorf("date %q is ambiguous, the day and month can't be told apart", removeExtraSpace(raw))
+		}
+		date := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+		if date.Day() == day {
+			return date, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("cannot read date %q", removeExtraSpace(raw))
+}
+
+// ParseExpiryDate reads an expiry cell into an ISO date. A shelf life ("12 month shelf life") counts from the
+// date the container was received; received is the zero time when that is not known.
+func ParseExpiryDate(raw string, received time.Time) (string, error) {
+	if strings.TrimSpace(raw) == "" {
+		return "", nil
+	}
+
+	if match := shelfLife.FindStringSubmatch(normaliseDateText(raw)); match != nil {
+		// the sheet sometimes works the date out already, e.g. "2 year shelf life (Nov 2023)"
+		if match[3] != "" {
+			if date, err := parseDate(match[3], true); err == nil {
+				return date.Format(isoDate), nil
+			}
+		}
+		if received.IsZero() {
+			return "", fmt.Errorf("shelf life %q needs a receipt date", removeExtraSpace(raw))
+		}
+		months, _ := strconv.Atoi(match[1])
+		if match[2] == "year" {
+			months *= 12
+		}
+		return received.AddDate(0, months, 0).Format(isoDate), nil
+	}
+
+	date, err := parseDate(raw, true)
+	if err != nil {
+		return "", err
+	}
+	return date.Format(isoDate), nil
+}
+
+// buildInstanceDates reads the expiry and manufacture dates of a row into ISO dates.
+// Cells that cannot be read are returned as problems and kept as text in the notes, so nothing is lost.
+func buildInstanceDates(row []string, cols *Columns) (expiry string, manufacture string, notes []string, problems []string) {
+	var received time.Time
+	if value := removeExtraSpace(cols.GetOptionalValueFromRow(row, cols.ReceivedDate, "")); value != "" {
+		date, err := parseDate(value, false)
+		if err != nil {
+			problems = append(problems, "receipt date: "+err.Error())
+		} else {
+			received = date
+		}
+	}
+
+	value := removeExtraSpace(cols.GetOptionalValueFromRow(row, cols.ManufactureDate, ""))
+	if value != "" {
+		date, err := parseDate(value, false)
+		if err != nil {
+			problems = append(problems, "manufacture date: "+err.Error())
+			notes = append(notes, "manufacture date: "+strings.Join(strings.Fields(value), " "))
+		} else {
+			manufacture = date.Format(isoDate)
+		}
+	}
+
+	value = cols.GetOptionalValueFromRow(row, cols.ExpirationDate, "")
+	expiry, err := ParseExpiryDate(value, received)
+	if err != nil {
+		problems = append(problems, "expiry date: "+err.Error())
+		notes = append(notes, "expiry date: "+strings.Join(strings.Fields(value), " "))
+	}
+
+	return expiry, manufacture, notes, problems
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseExpiryDate(t *testing.T) {
+	tests := map[string]string{
+		"Aug 31, 2026":                     "2026-08-31",
+		"2021/08/04":                       "2021-08-04",
+		"09/24":                            "2024-09-30",
+		"March 2021":                       "2021-03-31",
+		"Part A = Nov 2021":                "2021-11-30",
+		"Oct 21 2021(received after this)": "2021-10-21",
+		"Sept 13, 2024":                    "2024-09-13",
+		"0ct 9, 2024":                      "2024-10-09",
+		"April 01 2023":                    "2023-04-01",
+		"2 year shelf life (Nov 2023)":     "2023-11-30",
+		"13/07/2026":                       "2026-07-13",
+		"  ":                               "",
+	}
+	for raw, want := range tests {
+		got, err := ParseExpiryDate(raw, time.Time{})
+		if err != nil || got != want {
+			t.Errorf("ParseExpiryDate(%q) = %q, %v; want %q", raw, got, err, want)
+		}
+	}
+
+	for _, raw := range []string{"11/07/2026", "opened March 23, 2021", "estimated Dec 2021-July 2022", "12 month shelf life", "Feb 30, 2024"} {
+		if got, err := ParseExpiryDate(raw, time.Time{}); err == nil {
+			t.Errorf("ParseExpiryDate(%q) = %q, want an error", raw, got)
+		}
+	}
+}
+
+func TestParseExpiryDateShelfLife(t *testing.T) {
+	received := time.Date(2024, time.March, 15, 0, 0, 0, 0, time.UTC)
+	tests := map[string]string{
+		"12 month shelf life": "2025-03-15",
+		"6 month shelf life":  "2024-09-15",
+		"3 year shelf life":   "2027-03-15",
+	}
+	for raw, want := range tests {
+		got, err := ParseExpiryDate(raw, received)
+		if err != nil || got != want {
+			t.Errorf("ParseExpiryDate(%q, %s) = %q, %v; want %q", raw, received.Format(isoDate), got, err, want)
+		}
+	}
+}
+
+func TestBuildInstanceDates(t *testing.T) {
+	cols := NewColumns()
+	cols.ExpirationDate, cols.ReceivedDate = 0, 1
+
+	expiry, _, notes, problems := buildInstanceDates([]string{"12 month shelf life", "March 2024"}, cols)
+	if expiry != "2025-03-01" || len(notes) != 0 || len(problems) != 0 {
+		t.Errorf("got %q, notes %q, problems %q; want 2025-03-01", expiry, notes, problems)
+	}
+
+	expiry, _, notes, problems = buildInstanceDates([]string{"12 month shelf life", ""}, cols)
+	if expiry != "" || len(notes) != 1 || len(problems) != 1 {
+		t.Errorf("got %q, notes %q, problems %q; want the text kept in the notes", expiry, notes, problems)
+	}
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/miru-smart-technologies/Scripts/go/pkg/portal"
+)
+
+// DryRunClient wraps a PortalClient for a dry run. Reads go to the Portal; the create methods
+// record a planned action and return a placeholder ID instead, and the check methods also find planned records.
+type DryRunClient struct {
+	portal.PortalClient
+	plan *dryRunPlan
+}
+
+// dryRunPlan holds the placeholder IDs handed out so far, shared by the clients of all workers
+type dryRunPlan struct {
+	mu           sync.Mutex
+	placeholders map[string]bool
+}
+
+func NewDryRunClient(client portal.PortalClient) *DryRunClient {
+	return &DryRunClient{
+		PortalClient: client,
+		plan:         &dryRunPlan{placeholders: map[string]bool{}},
+	}
+}
+
+// WithClient returns a DryRunClient that shares the plan of d but reads through client, for another worker
+func (d *DryRunClient) WithClient(client portal.PortalClient) *DryRunClient {
+	return &DryRunClient{PortalClient: client, plan: d.plan}
+}
+
+// placeholderID derives a stable, valid UUID for a planned record so downstream payloads can still be built
+func placeholderID(plannedKey string) string {
+	return uuid.NewSHA1(uuid.NameSpaceOID, []byte("dry-run:"+plannedKey)).String()
+}
+
+// Plan records that the record of the given kind and key would be created and returns its placeholder ID
+func (d *DryRunClient) Plan(kind string, key string) string {
+	id := placeholderID(kind + ":" + key)
+	d.plan.mu.Lock()
+	defer d.plan.mu.Unlock()
+
+	d.plan.placeholders[id] = true
+	return id
+}
+
+// Planned returns the placeholder ID of a record planned earlier in the run
+func (d *DryRunClient) Planned(kind string, key string) (string, bool) {
+	id := placeholderID(kind + ":" + key)
+	return id, d.IsPlaceholder(id)
+}
+
+// IsPlaceholder reports whether id was handed out by the planner rather than by the Portal
+func (d *DryRunClient) IsPlaceholder(id string) bool {
+	d.plan.mu.Lock()
+	defer d.plan.mu.Unlock()
+
+	return d.plan.placeholders[id]
+}
+
+func (d *DryRunClient) CheckIfChemicalExists(name string) (bool, string, error) {
+	if id, ok := d.Planned("chemical", name); ok {
+		return true, id, nil
+	}
+	return d.PortalClient.CheckIfChemicalExists(name)
+}
+
+func (d *DryRunClient) CheckIfChemicalWithCasExists(cas string) (bool, string, error) {
+	if id, ok := d.Planned("chemical", "CAS "+cas); ok {
+		return true, id, nil
+	}
+	return d.PortalClient.CheckIfChemicalWithCasExists(cas)
+}
+
+// CheckIfChemicalWithNameAndCasExists finds the planned chemicals without a CAS number, which are planned by name,
+// and those with the CAS number
+func (d *DryRunClient) CheckIfChemicalWithNameAndCasExists(name string, cas string) (bool, string, error) {
+	if id, ok := d.Planned("chemical", name); ok {
+		return true, id, nil
+	}
+	if id, ok := d.Planned("chemical", "CAS "+cas); ok {
+		return true, id, nil
+	}
+	return d.PortalClient.CheckIfChemicalWithNameAndCasExists(name, cas)
+}
+
+// CreateChemical plans a chemical under the key the import looks it up by: its CAS number, or its name without one
+func (d *DryRunClient) CreateChemical(pChemical portal.PayloadChemical) (string, error) {
+	if pChemical.SafetyInfo.CasNumber != "" {
+		return d.Plan("chemical", "CAS "+pChemical.SafetyInfo.CasNumber), nil
+	}
+	return d.Plan("chemical", pChemical.Name), nil
+}
+
+func (d *DryRunClient) CheckIfChemicalRecipeExists(name string, chemicalID string) (bool, string, error) {
+	if id, ok := d.Planned("chemical recipe", chemicalID+"/"+name); ok {
+		return true, id, nil
+	}
+	// a chemical that only exists in the plan has no recipes in the DB yet
+	if d.IsPlaceholder(chemicalID) {
+		return false, "", nil
+	}
+	return d.PortalClient.CheckIfChemicalRecipeExists(name, chemicalID)
+}
+
+func (d *DryRunClient) CreateChemicalRecipe(pRecipe portal.PayloadChemicalRecipe) (string, error) {
+	return d.Plan("chemical recipe", pRecipe.ChemicalUUID.String()+"/"+pRecipe.Title), nil
+}
+
+func (d *DryRunClient) CheckIfChemicalInstanceExists(ciid int64) (bool, string, error) {
+	if id, ok := d.Planned("chemical instance", strconv.FormatInt(ciid, 10)); ok {
+		return true, id, nil
+	}
+	return d.PortalClient.CheckIfChemicalInstanceExists(ciid)
+}
+
+func (d *DryRunClient) CreateChemicalInstance(pInstance portal.PayloadChemicalInstance) (string, error) {
+	if pInstance.ID == 0 {
+		// further containers of a row get their CIID from the Portal
+		return d.Plan("chemical instance", pInstance.Label), nil
+	}
+	return d.Plan("chemical instance", strconv.FormatInt(pInstance.ID, 10)), nil
+}
+
+func (d *DryRunClient) CheckIfSupplierExists(name string) (bool, string, error) {
+	if id, ok := d.Planned("supplier", name); ok {
+		return true, id, nil
+	}
+	return d.PortalClient.CheckIfSupplierExists(name)
+}
+
+func (d *DryRunClient) CreateSupplier(pSupplier portal.PayloadSupplier) (string, error) {
+	return d.Plan("supplier", pSupplier.Name), nil
+}
+
+func (d *DryRunClient) CheckIfLocationExists(name string) (bool, string, error) {
+	if id, ok := d.Planned("location", name); ok {
+		return true, id, nil
+	}
+	return d.PortalClient.CheckIfLocationExists(name)
+}
+
+func (d *DryRunClient) CreateLocation(pLocation portal.PayloadLocation) (string, error) {
+	return d.Plan("location", pLocation.Name), nil
+}
+
+// capitalise upper-cases the first letter of a record kind for console output
+func capitalise(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+// logExisting records that a record is already in the Portal, or in dry-run mode (planner is not nil)
+// that it would be reused
+func logExisting(planner *DryRunClient, writer *LogWriter, rowNum int, kind string, name string, id string) {
+	step := "Check if " + kind + " already exists"
+
+	if planner == nil {
+		fmt.Printf("%s %s already exists in DB - skipping\n", capitalise(kind), name)
+		writeProcessedLog(writer, rowNum, step, "success", id, "")
+		return
+	}
+
+	if planner.IsPlaceholder(id) {
+		status := fmt.Sprintf("would reuse %s %s (planned earlier in this run)", kind, name)
+		fmt.Println(status)
+		writeProcessedLog(writer.withoutCall(), rowNum, step, status, "", "")
+		return
+	}
+
+	status := fmt.Sprintf("would reuse %s %s", kind, name)
+	fmt.Println(status)
+	writeProcessedLog(writer, rowNum, step, status, id, "")
+}
+
+// logCreated records that a record was created, or in dry-run mode (planner is not nil) that it would be created
+func logCreated(planner *DryRunClient, writer *LogWriter, rowNum int, kind string, name string, id string) {
+	step := "Create new " + kind
+
+	if planner == nil {
+		fmt.Printf("Created new %s %s with ID %s\n", kind, name, id)
+		writeProcessedLog(writer, rowNum, step, "success", id, "")
+		return
+	}
+
+	status := fmt.Sprintf("would create %s %s", kind, name)
+	fmt.Println(status)
+	writeProcessedLog(writer.withoutCall(), rowNum, step, status, "", "")
+}
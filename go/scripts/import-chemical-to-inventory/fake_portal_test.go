@@ -0,0 +1,475 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"unicode"
+
+	"github.com/google/uuid"
+	"github.com/miru-smart-technologies/Scripts/go/pkg/portal"
+)
+
+// fakePortal is an in-memory Portal behind an httptest server.
+// Like the real API it answers 500 when a looked-up record does not exist.
+type fakePortal struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	chemicals map[string]portal.PortalChemical         // by ID
+	created   []string                                 // chemical IDs in the order they were created
+	recipes   map[string]portal.PortalChemicalRecipe   // by ID
+	instances map[string]portal.PortalChemicalInstance // by UUID
+	suppliers map[string]portal.PortalSupplier         // by ID
+	locations map[string]portal.PortalLocation         // by ID
+	users     map[string]portal.PortalUser             // by ID
+	creates   int                                      // POST requests that created a record
+	nextCiid  int64                                    // CIID given to instances created without one
+
+	unavailablePosts int  // POST requests still to answer with 503, like a proxy while the Portal restarts
+	failInstanceAt   int  // the instance create to answer with a real 500, counting from 1, 0 for none
+	instanceCreates  int  // instance creates so far, failed ones included
+	failingGets      int  // GET requests still to answer with a real 500, like a database error
+	noCatalogue      bool // answer the list routes with 404, like a Portal without them
+	strict           bool // answer 404 for a missing record, like the updated API, instead of 500
+	noCasRoute       bool // answer the CAS number lookup with 404, like a Portal without the route
+	asciiNameLookups bool // find chemicals by name only if the name is ASCII, like a Portal that mangles other characters
+	chemicalLookups  int  // GET requests for one chemical or the recipes of one
+
+	failingDeletes map[string]bool // IDs whose delete is answered with a real 500
+}
+
+func newFakePortal(t *testing.T) *fakePortal {
+	t.Helper()
+
+	f := &fakePortal{
+		chemicals: map[string]portal.PortalChemical{},
+		recipes:   map[string]portal.PortalChemicalRecipe{},
+		instances: map[string]portal.PortalChemicalInstance{},
+		suppliers: map[string]portal.PortalSupplier{},
+		locations: map[string]portal.PortalLocation{},
+		users:     map[string]portal.PortalUser{},
+		nextCiid:  1000000,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /chemicals", f.listChemicals)
+	mux.HandleFunc("GET /recipes", f.listRecipes)
+	mux.HandleFunc("GET /chemicals/name", f.getChemicalByName)
+	mux.HandleFunc("GET /chemicals/cas", f.getChemicalByCas)
+	mux.HandleFunc("POST /chemicals", f.createChemical)
+	mux.HandleFunc("GET /chemicals/{id}/recipes", f.getRecipes)
+	mux.HandleFunc("POST /recipes/", f.createRecipe)
+	mux.HandleFunc("GET /instances/ciid", f.getInstanceByCiid)
+	mux.HandleFunc("POST /instances", f.createInstance)
+	mux.HandleFunc("GET /suppliers/name", f.getSupplierByName)
+	mux.HandleFunc("POST /suppliers", f.createSupplier)
+	mux.HandleFunc("GET /locations/name", f.getLocationByName)
+	mux.HandleFunc("POST /locations", f.createLocation)
+	mux.HandleFunc("GET /users/name", f.getUserByName)
+	mux.HandleFunc("GET /users/{id}", f.getUser)
+	mux.HandleFunc("DELETE /chemicals/{id}", deleteHandler(f, f.chemicals))
+	mux.HandleFunc("DELETE /recipes/{id}", deleteHandler(f, f.recipes))
+	mux.HandleFunc("DELETE /instances/{id}", deleteHandler(f, f.instances))
+	mux.HandleFunc("DELETE /suppliers/{id}", deleteHandler(f, f.suppliers))
+	mux.HandleFunc("DELETE /locations/{id}", deleteHandler(f, f.locations))
+
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		unavailable := r.Method == http.MethodPost && f.unavailablePosts > 0
+		if unavailable {
+			f.unavailablePosts--
+		}
+		failing := r.Method == http.MethodGet && f.failingGets > 0
+		if failing {
+			f.failingGets--
+		}
+		f.mu.Unlock()
+
+		if unavailable {
+			http.Error(w, "service unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		if failing {
+			http.Error(w, "database error", http.StatusInternalServerError)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(f.Close)
+	return f
+}
+
+// client returns a PortalClient talking to the fake, reading missing records the legacy way
+func (f *fakePortal) client() *portal.RestyClient {
+	return portal.NewPortalClient(portal.Config{BaseURL: f.URL})
+}
+
+// addUser registers a user the owner checks can find
+func (f *fakePortal) addUser(name string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	id := uuid.NewString()
+	f.users[id] = portal.PortalUser{ID: id, Name: name}
+	return id
+}
+
+// recordCount returns how many records of all kinds the fake holds
+func (f *fakePortal) recordCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return len(f.chemicals) + len(f.recipes) + len(f.instances) + len(f.suppliers) + len(f.locations)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// notFound answers the way the real API does for a missing record; the caller holds f.mu
+func (f *fakePortal) notFound(w http.ResponseWriter) {
+	if f.strict {
+		http.Error(w, "record not found", http.StatusNotFound)
+		return
+	}
+	http.Error(w, "record not found", http.StatusInternalServerError)
+}
+
+func decodeBody(w http.ResponseWriter, r *http.Request, v any) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+func (f *fakePortal) listChemicals(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.noCatalogue {
+		http.NotFound(w, r)
+		return
+	}
+	chemicals := []portal.PortalChemical{}
+	for _, chemical := range f.chemicalsInOrder() {
+		chemicals = append(chemicals, chemical)
+	}
+	writeJSON(w, http.StatusOK, chemicals)
+}
+
+func (f *fakePortal) listRecipes(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.noCatalogue {
+		http.NotFound(w, r)
+		return
+	}
+	recipes := []portal.PortalChemicalRecipe{}
+	for _, recipe := range f.recipes {
+		recipes = append(recipes, recipe)
+	}
+	writeJSON(w, http.StatusOK, recipes)
+}
+
+// chemicalsInOrder returns the chemicals in the order they were created, so that like in the Portal
+// the first chemical of a name or CAS number is the one a lookup finds
+func (f *fakePortal) chemicalsInOrder() []portal.PortalChemical {
+	var chemicals []portal.PortalChemical
+	for _, id := range f.created {
+		if chemical, ok := f.chemicals[id]; ok {
+			chemicals = append(chemicals, chemical)
+		}
+	}
+	return chemicals
+}
+
+func (f *fakePortal) getChemicalByName(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.chemicalLookups++
+
+	name := r.URL.Query().Get("name")
+	if f.asciiNameLookups && strings.IndexFunc(name, func(c rune) bool { return c > unicode.MaxASCII }) >= 0 {
+		f.notFound(w)
+		return
+	}
+	for _, chemical := range f.chemicalsInOrder() {
+		if chemical.Name == name {
+			writeJSON(w, http.StatusOK, chemical)
+			return
+		}
+	}
+	f.notFound(w)
+}
+
+func (f *fakePortal) getChemicalByCas(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.chemicalLookups++
+
+	if f.noCasRoute {
+		http.NotFound(w, r)
+		return
+	}
+	cas := r.URL.Query().Get("cas")
+	for _, chemical := range f.chemicalsInOrder() {
+		if chemical.SafetyInfo.CasNumber == cas {
+			writeJSON(w, http.StatusOK, chemical)
+			return
+		}
+	}
+	f.notFound(w)
+}
+
+func (f *fakePortal) createChemical(w http.ResponseWriter, r *http.Request) {
+	var payload portal.PayloadChemical
+	if !decodeBody(w, r, &payload) {
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	chemical := portal.PortalChemical{
+		ID:          uuid.NewString(),
+		Name:        payload.Name,
+		Description: payload.Description,
+		SafetyInfo:  payload.SafetyInfo,
+	}
+	if payload.StateOfMatter != nil {
+		chemical.StateOfMatter = *payload.StateOfMatter
+	}
+	if payload.Colour != nil {
+		chemical.Colour = *payload.Colour
+	}
+	if payload.MolecularWeight != nil {
+		chemical.MolecularWeight = *payload.MolecularWeight
+	}
+	if payload.Density != nil {
+		chemical.Density = *payload.Density
+	}
+	f.chemicals[chemical.ID] = chemical
+	f.created = append(f.created, chemical.ID)
+	f.creates++
+	writeJSON(w, http.StatusCreated, chemical)
+}
+
+func (f *fakePortal) getRecipes(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.chemicalLookups++
+
+	chemicalID := r.PathValue("id")
+	if _, ok := f.chemicals[chemicalID]; !ok {
+		f.notFound(w)
+		return
+	}
+
+	recipes := []portal.PortalChemicalRecipe{}
+	for _, recipe := range f.recipes {
+		if recipe.ChemicalUUID.String() == chemicalID {
+			recipes = append(recipes, recipe)
+		}
+	}
+	writeJSON(w, http.StatusOK, recipes)
+}
+
+func (f *fakePortal) createRecipe(w http.ResponseWriter, r *http.Request) {
+	var payload portal.PayloadChemicalRecipe
+	if !decodeBody(w, r, &payload) {
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.chemicals[payload.ChemicalUUID.String()]; !ok {
+		http.Error(w, "unknown chemical", http.StatusBadRequest)
+		return
+	}
+
+	recipe := portal.PortalChemicalRecipe{
+		ID:           uuid.NewString(),
+		Title:        payload.Title,
+		ChemicalUUID: payload.ChemicalUUID,
+		Components:   payload.Components,
+	}
+	f.recipes[recipe.ID] = recipe
+	f.creates++
+	writeJSON(w, http.StatusCreated, recipe)
+}
+
+func (f *fakePortal) getInstanceByCiid(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	ciid, err := strconv.ParseInt(r.URL.Query().Get("ciid"), 10, 64)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	for _, instance := range f.instances {
+		if instance.ID == ciid {
+			writeJSON(w, http.StatusOK, instance)
+			return
+		}
+	}
+	f.notFound(w)
+}
+
+func (f *fakePortal) createInstance(w http.ResponseWriter, r *http.Request) {
+	var payload portal.PayloadChemicalInstance
+	if !decodeBody(w, r, &payload) {
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.recipes[payload.RecipeUUID.String()]; !ok {
+		http.Error(w, "unknown recipe", http.StatusBadRequest)
+		return
+	}
+
+	f.instanceCreates++
+	if f.instanceCreates == f.failInstanceAt {
+		http.Error(w, "database error", http.StatusInternalServerError)
+		return
+	}
+
+	instance := portal.PortalChemicalInstance{
+		UUID:             uuid.New(),
+		ID:               payload.ID,
+		RecipeUUID:       payload.RecipeUUID,
+		Amount:           payload.Amount,
+		Unit:             payload.Unit,
+		Owner:            payload.Owner,
+		HomeLocationUUID: payload.HomeLocationUUID,
+		SupplierUUID:     payload.SupplierUUID,
+		ParentUUID:       payload.ParentUUID,
+		ManufactureDate:  payload.ManufactureDate,
+		ExpirationDate:   payload.ExpirationDate,
+		LotNumber:        payload.LotNumber,
+		Label:            payload.Label,
+		Notes:            payload.Notes,
+	}
+	if instance.ID == 0 {
+		f.nextCiid++
+		instance.ID = f.nextCiid
+	}
+	f.instances[instance.UUID.String()] = instance
+	f.creates++
+	writeJSON(w, http.StatusCreated, instance)
+}
+
+func (f *fakePortal) getSupplierByName(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	name := r.URL.Query().Get("name")
+	for _, supplier := range f.suppliers {
+		if supplier.Name == name {
+			writeJSON(w, http.StatusOK, supplier)
+			return
+		}
+	}
+	f.notFound(w)
+}
+
+func (f *fakePortal) createSupplier(w http.ResponseWriter, r *http.Request) {
+	var payload portal.PayloadSupplier
+	if !decodeBody(w, r, &payload) {
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	supplier := portal.PortalSupplier{ID: uuid.NewString(), Name: payload.Name}
+	f.suppliers[supplier.ID] = supplier
+	f.creates++
+	writeJSON(w, http.StatusCreated, supplier)
+}
+
+func (f *fakePortal) getLocationByName(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	name := r.URL.Query().Get("name")
+	for _, location := range f.locations {
+		if location.Name == name {
+			writeJSON(w, http.StatusOK, location)
+			return
+		}
+	}
+	f.notFound(w)
+}
+
+func (f *fakePortal) createLocation(w http.ResponseWriter, r *http.Request) {
+	var payload portal.PayloadLocation
+	if !decodeBody(w, r, &payload) {
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	location := portal.PortalLocation{ID: uuid.NewString(), Name: payload.Name}
+	f.locations[location.ID] = location
+	f.creates++
+	writeJSON(w, http.StatusCreated, location)
+}
+
+func (f *fakePortal) getUser(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if user, ok := f.users[r.PathValue("id")]; ok {
+		writeJSON(w, http.StatusOK, user)
+		return
+	}
+	f.notFound(w)
+}
+
+func (f *fakePortal) getUserByName(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	name := r.URL.Query().Get("name")
+	for _, user := range f.users {
+		if user.Name == name {
+			writeJSON(w, http.StatusOK, user)
+			return
+		}
+	}
+	f.notFound(w)
+}
+
+// deleteHandler deletes a record of one kind by the ID in the path
+func deleteHandler[T any](f *fakePortal, records map[string]T) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+
+		id := r.PathValue("id")
+		if f.failingDeletes[id] {
+			http.Error(w, "database error", http.StatusInternalServerError)
+			return
+		}
+		if _, ok := records[id]; !ok {
+			f.notFound(w)
+			return
+		}
+		delete(records, id)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
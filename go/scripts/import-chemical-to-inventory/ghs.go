@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/miru-smart-technologies/Scripts/go/pkg/portal"
+)
+
+// ghsHazardClass is a GHS hazard class with its COLUMN_GHS_* mapping key and the categories it has
+type ghsHazardClass struct {
+	key        string
+	name       string
+	categories []string
+}
+
+// ghsHazardClasses lists the GHS hazard classes of the sheet, in sheet order.
+// Categories follow GHS rev. 9 plus the OSHA HazCom classes (HNOC, PHNOC, combustible dusts).
+var ghsHazardClasses = []ghsHazardClass{
+	{"COLUMN_GHS_ACUTE_TOXICITY_ORAL", "Acute toxicity (oral)", []string{"1", "2", "3", "4", "5"}},
+	{"COLUMN_GHS_ACUTE_TOXICITY_DERMAL", "Acute toxicity (dermal)", []string{"1", "2", "3", "4", "5"}},
+	{"COLUMN_GHS_ACUTE_TOXICITY_INHALATION", "Acute toxicity (inhalation)", []string{"1", "2", "3", "4", "5"}},
+	{"COLUMN_GHS_SKIN_CORROSION_IRRITATION", "Skin corrosion/irritation", []string{"1", "1A", "1B", "1C", "2", "3"}},
+	{"COLUMN_GHS_SERIOUS_EYE_DAMAGE_IRRITATION", "Serious eye damage/eye irritation", []string{"1", "2", "2A", "2B"}},
+	{"COLUMN_GHS_RESPIRATORY_SENSITIZATION", "Respiratory sensitization", []string{"1", "1A", "1B"}},
+	{"COLUMN_GHS_SKIN_SENSITIZATION", "Skin sensitization", []string{"1", "1A", "1B"}},
+	{"COLUMN_GHS_GERM_CELL_MUTAGENICITY", "Germ cell mutagenicity", []string{"1", "1A", "1B", "2"}},
+	{"COLUMN_GHS_CARCINOGENICITY", "Carcinogenicity", []string{"1", "1A", "1B", "2"}},
+	{"COLUMN_GHS_REPRODUCTIVE_TOXICITY", "Reproductive toxicity", []string{"1", "1A", "1B", "2", "Lact"}},
+	{"COLUMN_GHS_STOT_SINGLE_EXPOSURE", "Specific target organ toxicity (single exposure)", []string{"1", "2", "3"}},
+	{"COLUMN_GHS_STOT_REPEATED_EXPOSURE", "Specific target organ toxicity (repeated exposure)", []string{"1", "2"}},
+	{"COLUMN_GHS_ASPIRATION_HAZARD", "Aspiration hazard", []string{"1", "2"}},
+	{"COLUMN_GHS_HEALTH_HAZARD_NOC", "Health hazard not otherwise classified", []string{"1"}},
+	{"COLUMN_GHS_FLAMMABLE_LIQUIDS", "Flammable liquids", []string{"1", "2", "3", "4"}},
+	{"COLUMN_GHS_FLAMMABLE_SOLIDS", "Flammable solids", []string{"1", "2"}},
+	{"COLUMN_GHS_SELF_REACTIVE", "Self-reactive substances and mixtures", []string{"Type A", "Type B", "Type C", "Type D", "Type E", "Type F", "Type G"}},
+	{"COLUMN_GHS_CORROSIVE_TO_METALS", "Corrosive to metals", []string{"1"}},
+	{"COLUMN_GHS_SELF_HEATING", "Self-heating substances and mixtures", []string{"1", "2"}},
+	{"COLUMN_GHS_OXIDIZING_LIQUIDS", "Oxidizing liquids", []string{"1", "2", "3"}},
+	{"COLUMN_GHS_OXIDIZING_SOLIDS", "Oxidizing solids", []string{"1", "2", "3"}},
+	{"COLUMN_GHS_COMBUSTIBLE_DUSTS", "Combustible dusts", []string{"1"}},
+	{"COLUMN_GHS_FIRE_OR_PROJECTION_HAZARD", "Explosives", []string{"1.1", "1.2", "1.3", "1.4", "1.5", "1.6"}},
+	{"COLUMN_GHS_PHYSICAL_HAZARD_NOC", "Physical hazard not otherwise classified", []string{"1"}},
+	{"COLUMN_GHS_AQUATIC_ACUTE", "Hazardous to the aquatic environment (acute)", []string{"1", "2", "3"}},
+	{"COLUMN_GHS_AQUATIC_CHRONIC", "Hazardous to the aquatic environment (chronic)", []string{"1", "2", "3", "4"}},
+}
+
+// ghsFlammableLiquids is the index of the hazard class the older COLUMN_GHS_FLAMMABLE_LIQUID_CATEGORY column holds
+var ghsFlammableLiquids = ghsHazardClassIndex("COLUMN_GHS_FLAMMABLE_LIQUIDS")
+
+func ghsHazardClassIndex(key string) int {
+	for i, class := range ghsHazardClasses {
+		if class.key == key {
+			return i
+		}
+	}
+	panic("unknown GHS hazard class " + key)
+}
+
+var (
+	// trailingZeros matches the "4.000" and "2.0" the sheet has where a number format was applied to a category
+	trailingZeros = regexp.MustCompile(`^(\d)\.0+$`)
+	// categoryPrefix matches a spelled out "Category 2" / "Cat. 2"
+	categoryPrefix = regexp.MustCompile(`^(category|cat\.?)\s*`)
+	// categorySeparator splits cells with several categories, e.g. "1,2" or "1 and 2" for STOT
+	categorySeparator = regexp.MustCompile(`\s*(,|;|/|\band\b)\s*`)
+)
+
+// normaliseGhsCategory brings a category cell into the form used in ghsHazardClasses, e.g. "1.00" -> "1", "type c" -> "Type C"
+func normaliseGhsCategory(raw string) string {
+	category := strings.ToLower(strings.Join(strings.Fields(raw), " "))
+	category = categoryPrefix.ReplaceAllString(category, "")
+	category = trailingZeros.ReplaceAllString(category, "$1")
+
+	switch {
+	case category == "combustible dust":
+		return "1"
+	case category == "lact":
+		return "Lact"
+	case strings.HasPrefix(category, "type "):
+		return "Type " + strings.ToUpper(strings.TrimPrefix(category, "type "))
+	}
+	return strings.ToUpper(category)
+}
+
+// parseGhsCategories validates a category cell against the categories of its hazard class.
+// A cell can hold several categories; if any of them is not valid for the class the whole cell is rejected.
+func parseGhsCategories(class ghsHazardClass, raw string) ([]string, error) {
+	var categories []string
+	for _, part := range categorySeparator.Split(strings.TrimSpace(raw), -1) {
+		category := normaliseGhsCategory(part)
+		valid := false
+		for _, allowed := range class.categories {
+			if category == allowed {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return nil, fmt.Errorf("%s: %q is not a category of this class (expected one of %s)",
+				class.name, removeExtraSpace(raw), strings.Join(class.categories, ", "))
+		}
+		categories = append(categories, category)
+	}
+	return categories, nil
+}
+
+// buildGhsHazards reads the GHS classification of a row.
+// Cells that are not a valid category are returned as problems and kept as text in the notes, so nothing is lost.
+func buildGhsHazards(row []string, cols *Columns) (hazards []portal.PortalGhsHazard, notes []string, problems []string) {
+	flammableLiquids := false
+
+	for i, class := range ghsHazardClasses {
+		value := removeExtraSpace(cols.GetOptionalValueFromRow(row, cols.GhsHazards[i], ""))
+		if value == "" {
+			continue
+		}
+
+		categories, err := parseGhsCategories(class, value)
+		if err != nil {
+			problems = append(problems, err.Error())
+			notes = append(notes, "GHS "+class.name+": "+strings.Join(strings.Fields(value), " "))
+			continue
+		}
+		for _, category := range categories {
+			hazards = append(hazards, portal.PortalGhsHazard{HazardClass: class.name, Category: category})
+		}
+		if i == ghsFlammableLiquids {
+			flammableLiquids = true
+		}
+	}
+
+	// the older free-text flammable liquid column: a plain category counts unless the GHS columns already have one,
+	// "n/a" is dropped and anything else (NFPA ratings, remarks) stays a note as before
+	value := removeExtraSpace(cols.GetOptionalValueFromRow(row, cols.GhsFlammableLiquidCategory, ""))
+	if value != "" && !strings.EqualFold(value, "n/a") {
+		categories, err := parseGhsCategories(ghsHazardClasses[ghsFlammableLiquids], value)
+		switch {
+		case err != nil:
+			notes = append(notes, "GHS Flammable liquid category: "+value)
+		case !flammableLiquids:
+			for _, category := range categories {
+				hazards = append(hazards, portal.PortalGhsHazard{HazardClass: ghsHazardClasses[ghsFlammableLiquids].name, Category: category})
+			}
+		}
+	}
+
+	return hazards, notes, problems
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/miru-smart-technologies/Scripts/go/pkg/portal"
+)
+
+func TestParseGhsCategories(t *testing.T) {
+	tests := []struct {
+		key  string
+		raw  string
+		want []string // nil if invalid
+	}{
+		{"COLUMN_GHS_ACUTE_TOXICITY_ORAL", "4", []string{"4"}},
+		{"COLUMN_GHS_ACUTE_TOXICITY_ORAL", "4.000", []string{"4"}},
+		{"COLUMN_GHS_ACUTE_TOXICITY_ORAL", "Category 3", []string{"3"}},
+		{"COLUMN_GHS_ACUTE_TOXICITY_ORAL", "6", nil},
+		{"COLUMN_GHS_SKIN_CORROSION_IRRITATION", "1b", []string{"1B"}},
+		{"COLUMN_GHS_SKIN_CORROSION_IRRITATION", "1 (A,B,C)", nil},
+		{"COLUMN_GHS_SERIOUS_EYE_DAMAGE_IRRITATION", " 2A ", []string{"2A"}},
+		{"COLUMN_GHS_SERIOUS_EYE_DAMAGE_IRRITATION", "2C", nil},
+		{"COLUMN_GHS_STOT_SINGLE_EXPOSURE", "1,3", []string{"1", "3"}},
+		{"COLUMN_GHS_STOT_REPEATED_EXPOSURE", "1 and 2", []string{"1", "2"}},
+		{"COLUMN_GHS_SELF_REACTIVE", "Type C", []string{"Type C"}},
+		{"COLUMN_GHS_SELF_REACTIVE", "C", nil},
+		{"COLUMN_GHS_COMBUSTIBLE_DUSTS", "combustible dust", []string{"1"}},
+		{"COLUMN_GHS_FIRE_OR_PROJECTION_HAZARD", "1.4", []string{"1.4"}},
+		{"COLUMN_GHS_PHYSICAL_HAZARD_NOC", "1 Reacts violently with water", nil},
+	}
+
+	for _, tt := range tests {
+		got, err := parseGhsCategories(ghsHazardClasses[ghsHazardClassIndex(tt.key)], tt.raw)
+		if tt.want == nil {
+			if err == nil {
+				t.Errorf("%s %q: got %v, want an error", tt.key, tt.raw, got)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s %q = %v, %v; want %v", tt.key, tt.raw, got, err, tt.want)
+		}
+	}
+}
+
+func TestBuildGhsHazards(t *testing.T) {
+	cols := NewColumns()
+	cols.GhsFlammableLiquidCategory = 0
+	cols.GhsHazards[ghsHazardClassIndex("COLUMN_GHS_ACUTE_TOXICITY_ORAL")] = 1
+	cols.GhsHazards[ghsHazardClassIndex("COLUMN_GHS_CARCINOGENICITY")] = 2
+
+	hazards, notes, problems := buildGhsHazards([]string{"2", "4.0", "1 maybe"}, cols)
+
+	wantHazards := []portal.PortalGhsHazard{
+		{HazardClass: "Acute toxicity (oral)", Category: "4"},
+		{HazardClass: "Flammable liquids", Category: "2"},
+	}
+	if !reflect.DeepEqual(hazards, wantHazards) {
+		t.Errorf("hazards = %v, want %v", hazards, wantHazards)
+	}
+	if want := []string{"GHS Carcinogenicity: 1 maybe"}; !reflect.DeepEqual(notes, want) {
+		t.Errorf("notes = %v, want %v", notes, want)
+	}
+	if len(problems) != 1 {
+		t.Errorf("problems = %v, want 1", problems)
+	}
+
+	// free text in the older flammable liquid column stays a note
+	_, notes, problems = buildGhsHazards([]string{"NFPA rating=1", "", ""}, cols)
+	if want := []string{"GHS Flammable liquid category: NFPA rating=1"}; !reflect.DeepEqual(notes, want) || len(problems) != 0 {
+		t.Errorf("notes = %v, problems = %v; want %v and none", notes, problems, want)
+	}
+}
@@ -6,7 +6,14 @@ require github.com/google/uuid v1.6.0
 
 require (
 	github.com/joho/godotenv v1.5.1
-	resty.dev/v3 v3.0.0-beta.3
+	github.com/miru-smart-technologies/Scripts/go/pkg v0.0.0
 )
 
-require golang.org/x/net v0.33.0 // indirect
+require (
+	golang.org/x/net v0.33.0 // indirect
+	golang.org/x/text v0.21.0 // indirect
+	resty.dev/v3 v3.0.0-beta.3 // indirect
+)
+
+// the shared packages live in this repo, next to the scripts
+replace github.com/miru-smart-technologies/Scripts/go/pkg => ../../pkg
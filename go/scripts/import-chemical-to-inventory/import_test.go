@@ -0,0 +1,857 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/miru-smart-technologies/Scripts/go/pkg/portal"
+)
+
+var importFixtures = []struct {
+	csvFile string
+	layout  string
+}{
+	{"chemicals-05-20-16-55.csv", "2025-05-20"},
+	{"chemicals-05-20-16-36.csv", "2025-05-20-draft"},
+	{"chemicals-05-12-11-17.csv", "2025-05-12"},
+}
+
+// testOptions returns the options of a run over csvFile against the fake, logging into a temp dir
+func testOptions(t *testing.T, fake *fakePortal, csvFile string) *Options {
+	t.Helper()
+
+	return &Options{
+		CsvFile:         csvFile,
+		MappingFile:     "chemical_inventory.env",
+		LocationAliases: "location_aliases.csv",
+		ChemicalAliases: "chemical_aliases.csv",
+		LayoutsDir:      "layouts",
+		ApiBaseUrl:      fake.URL,
+		LogFile:         filepath.Join(t.TempDir(), "log.csv"),
+		Stage:           StageTest,
+		Preload:         true,
+		NotFound:        NotFoundAuto,
+	}
+}
+
+func testColumns(t *testing.T, opts *Options) *Columns {
+	t.Helper()
+
+	cols := NewColumns()
+	if err := cols.LoadFromEnv(opts.MappingFile); err != nil {
+		t.Fatal(err)
+	}
+	if err := cols.ApplyOptions(opts); err != nil {
+		t.Fatal(err)
+	}
+	return cols
+}
+
+func runTestImport(t *testing.T, fake *fakePortal, opts *Options) (*Summary, [][]string) {
+	t.Helper()
+
+	client := fake.client()
+	if err := applyNotFoundMode(client, opts.NotFound); err != nil {
+		t.Fatal(err)
+	}
+	summary, err := runImport(opts, testColumns(t, opts), client)
+	if err != nil {
+		t.Fatalf("runImport: %v", err)
+	}
+
+	return summary, readCsv(t, opts.LogFile)
+}
+
+func readCsv(t *testing.T, filename string) [][]string {
+	t.Helper()
+
+	file, err := os.Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return records
+}
+
+// countLog counts the processed log rows with the given step and status
+func countLog(records [][]string, step string, status string) int {
+	count := 0
+	for _, record := range records[1:] {
+		if record[1] == step && record[2] == status {
+			count++
+		}
+	}
+	return count
+}
+
+// writeTestCsv writes a CSV with the header row of template and one row per map of header -> value
+func writeTestCsv(t *testing.T, template string, rows []map[string]string) string {
+	t.Helper()
+
+	header, err := readCsvHeader(template)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	csvFile := filepath.Join(t.TempDir(), "chemicals.csv")
+	file, err := os.Create(csvFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	writer.Write(header)
+	for _, values := range rows {
+		record := make([]string, len(header))
+		for name, value := range values {
+			index, err := findHeader(header, name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			record[index] = value
+		}
+		writer.Write(record)
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		t.Fatal(err)
+	}
+	return csvFile
+}
+
+func TestImportMatchesProcessedLog(t *testing.T) {
+	for _, fixture := range importFixtures {
+		t.Run(fixture.csvFile, func(t *testing.T) {
+			fake := newFakePortal(t)
+			summary, records := runTestImport(t, fake, testOptions(t, fake, fixture.csvFile))
+
+			if summary.Layout != fixture.layout {
+				t.Errorf("Layout = %q, want %q", summary.Layout, fixture.layout)
+			}
+			if got, want := summary.RowCount, len(readCsv(t, fixture.csvFile))-1; got != want {
+				t.Errorf("RowCount = %d, want %d", got, want)
+			}
+			if !summary.IsErrorCountConsistent() {
+				t.Errorf("error count %d does not match its breakdown", summary.ErrorCount)
+			}
+			if summary.CreatedChemicalCount == 0 {
+				t.Errorf("no chemicals created")
+			}
+
+			created := []struct {
+				kind  string
+				count int
+			}{
+				{"chemical", summary.CreatedChemicalCount},
+				{"chemical recipe", summary.CreatedRecipeCount},
+				{"supplier", summary.CreatedSupplierCount},
+				{"location", summary.CreatedLocationCount},
+				{"chemical instance", summary.CreatedInstanceCount},
+			}
+			total := 0
+			for _, c := range created {
+				got := 0
+				for _, record := range records[1:] {
+					// the containers of a row after the first are logged as "chemical instance container 2" and so on
+					if kind, ok := strings.CutPrefix(record[1], "Create new "); ok && recordKind(kind) == c.kind && record[2] == "success" {
+						got++
+					}
+				}
+				if got != c.count {
+					t.Errorf("log has %d created %ss, summary says %d", got, c.kind, c.count)
+				}
+				total += c.count
+			}
+			if fake.recordCount() != total {
+				t.Errorf("fake Portal holds %d records, summary says %d were created", fake.recordCount(), total)
+			}
+
+			if got := countLog(records, "Validate transport classification", "invalid transport classification"); got != summary.InvalidTransportCount {
+				t.Errorf("log has %d invalid transport classifications, summary says %d", got, summary.InvalidTransportCount)
+			}
+			if got := countLog(records, "Validate GHS hazards", "invalid GHS category"); got != summary.InvalidGhsCategoryCount {
+				t.Errorf("log has %d invalid GHS categories, summary says %d", got, summary.InvalidGhsCategoryCount)
+			}
+			if got := countLog(records, "Validate physical properties", "invalid physical property"); got != summary.InvalidPropertyCount {
+				t.Errorf("log has %d invalid physical properties, summary says %d", got, summary.InvalidPropertyCount)
+			}
+			if got := countLog(records, "Validate amount", "unparseable amount"); got != summary.UnparseableAmountCount {
+				t.Errorf("log has %d unparseable amounts, summary says %d", got, summary.UnparseableAmountCount)
+			}
+			if got := countLog(records, "Validate dates", "invalid date"); got != summary.InvalidDateCount {
+				t.Errorf("log has %d invalid dates, summary says %d", got, summary.InvalidDateCount)
+			}
+			if got := countLog(records, "Validate row", "missing recipe title"); got != summary.EmptyRecipeCount {
+				t.Errorf("log has %d empty recipe rows, summary says %d", got, summary.EmptyRecipeCount)
+			}
+		})
+	}
+}
+
+func TestImportRefusesUnknownLayout(t *testing.T) {
+	csvFile := filepath.Join(t.TempDir(), "chemicals.csv")
+	if err := os.WriteFile(csvFile, []byte("Name,CAS\n1-butanol,71-36-3\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	fake := newFakePortal(t)
+	opts := testOptions(t, fake, csvFile)
+	_, err := runImport(opts, testColumns(t, opts), fake.client())
+	if err == nil || !strings.Contains(err.Error(), "unknown sheet layout") {
+		t.Fatalf("runImport on an unknown layout: err = %v", err)
+	}
+	if fake.creates != 0 {
+		t.Errorf("created %d records", fake.creates)
+	}
+}
+
+func TestImportSecondRunCreatesNothing(t *testing.T) {
+	fake := newFakePortal(t)
+	first, _ := runTestImport(t, fake, testOptions(t, fake, "chemicals-05-20-16-55.csv"))
+	creates := fake.creates
+
+	second, records := runTestImport(t, fake, testOptions(t, fake, "chemicals-05-20-16-55.csv"))
+	if fake.creates != creates {
+		t.Errorf("second run created %d records", fake.creates-creates)
+	}
+	if second.CreatedChemicalCount != 0 || second.CreatedRecipeCount != 0 {
+		t.Errorf("second run reports %d chemicals and %d recipes created", second.CreatedChemicalCount, second.CreatedRecipeCount)
+	}
+	if got := countLog(records, "Check if chemical already exists", "success"); got < first.CreatedChemicalCount {
+		t.Errorf("second run found %d existing chemicals, want at least %d", got, first.CreatedChemicalCount)
+	}
+}
+
+func TestImportDryRunWritesNothing(t *testing.T) {
+	fake := newFakePortal(t)
+	opts := testOptions(t, fake, "chemicals-05-20-16-55.csv")
+	opts.DryRun = true
+
+	summary, records := runTestImport(t, fake, opts)
+	if fake.creates != 0 {
+		t.Errorf("dry run created %d records", fake.creates)
+	}
+	if got := countLog(records, "Create new chemical", "success"); got != 0 {
+		t.Errorf("plan has %d successful creates", got)
+	}
+	if summary.CreatedChemicalCount == 0 {
+		t.Errorf("plan creates no chemicals")
+	}
+}
+
+func TestRollbackDeletesWhatTheRunCreated(t *testing.T) {
+	fake := newFakePortal(t)
+	opts := testOptions(t, fake, "chemicals-05-20-16-55.csv")
+	runTestImport(t, fake, opts)
+
+	if err := RunRollback(rollbackOptions(t, fake, opts.LogFile), fake.client()); err != nil {
+		t.Fatal(err)
+	}
+
+	if fake.recordCount() != 0 {
+		t.Errorf("fake Portal still holds %d records after rollback", fake.recordCount())
+	}
+}
+
+// rollbackOptions returns the options of a rollback of the run that logged to logFile
+func rollbackOptions(t *testing.T, fake *fakePortal, logFile string) *RollbackOptions {
+	return &RollbackOptions{
+		LogFile:    logFile,
+		ApiBaseUrl: fake.URL,
+		Stage:      StageTest,
+		OutputFile: filepath.Join(t.TempDir(), "rollback.csv"),
+		Yes:        true,
+	}
+}
+
+func TestRollbackKeepsWhatFailedDeletesPointAt(t *testing.T) {
+	csvFile := writeTestCsv(t, "chemicals-05-20-16-55.csv", []map[string]string{
+		{"Row number": "1", "CIID": "1", "Chemical Name": "acetone", "Recipe": "99%"},
+		{"Row number": "2", "CIID": "2", "Chemical Name": "ethanol", "Recipe": "99%"},
+	})
+	fake := newFakePortal(t)
+	opts := testOptions(t, fake, csvFile)
+	runTestImport(t, fake, opts)
+
+	var acetoneInstance string
+	for id, instance := range fake.instances {
+		if instance.ID == 1 {
+			acetoneInstance = id
+		}
+	}
+	fake.failingDeletes = map[string]bool{acetoneInstance: true}
+
+	rollbackOpts := rollbackOptions(t, fake, opts.LogFile)
+	if err := RunRollback(rollbackOpts, fake.client()); err == nil {
+		t.Error("rollback returned no error, want the failed delete reported")
+	}
+
+	// the acetone instance, its recipe and chemical stay; ethanol is gone
+	if len(fake.instances) != 1 || len(fake.recipes) != 1 || len(fake.chemicals) != 1 {
+		t.Errorf("%d instances, %d recipes, %d chemicals left, want those of acetone", len(fake.instances), len(fake.recipes), len(fake.chemicals))
+	}
+	for _, chemical := range fake.chemicals {
+		if chemical.Name != "acetone" {
+			t.Errorf("chemical %q left, want acetone", chemical.Name)
+		}
+	}
+	log := readCsv(t, rollbackOpts.OutputFile)
+	if countLog(log, "Delete chemical recipe", "skipped") != 1 || countLog(log, "Delete chemical", "skipped") != 1 {
+		t.Errorf("rollback log doesn't show the recipe and chemical of acetone skipped")
+	}
+}
+
+func TestRollbackTreatsMissingRecordsAsDeleted(t *testing.T) {
+	fake := newFakePortal(t)
+	fake.strict = true
+	opts := testOptions(t, fake, "chemicals-05-20-16-55.csv")
+	opts.NotFound = "strict"
+	runTestImport(t, fake, opts)
+
+	// someone deleted a chemical by hand already
+	for id := range fake.chemicals {
+		delete(fake.chemicals, id)
+		break
+	}
+
+	if err := RunRollback(rollbackOptions(t, fake, opts.LogFile), fake.client()); err != nil {
+		t.Fatal(err)
+	}
+	if fake.recordCount() != 0 {
+		t.Errorf("fake Portal still holds %d records after rollback", fake.recordCount())
+	}
+}
+
+func TestImportMatchesChemicalsByCasNumber(t *testing.T) {
+	csvFile := writeTestCsv(t, "chemicals-05-20-16-55.csv", []map[string]string{
+		{"Row number": "1", "CIID": "1", "Chemical Name": "1-butanol", "CAS Number": "71-36-3"},
+		{"Row number": "2", "CIID": "2", "Chemical Name": "n-Butanol", "CAS Number": "71-36-3"},
+		{"Row number": "3", "CIID": "3", "Chemical Name": "sulfolane", "CAS Number": "126-33-0"},
+		{"Row number": "4", "CIID": "4", "Chemical Name": "sulfolane", "CAS Number": ""},
+		{"Row number": "5", "CIID": "5", "Chemical Name": "lithium salt", "CAS Number": "1394595-45-5"},
+	})
+
+	fake := newFakePortal(t)
+	summary, records := runTestImport(t, fake, testOptions(t, fake, csvFile))
+
+	// 1-butanol and n-butanol are one chemical; the sulfolane without a CAS number is only found by name
+	if summary.CreatedChemicalCount != 3 {
+		t.Errorf("created %d chemicals, want 3", summary.CreatedChemicalCount)
+	}
+	if summary.InvalidCasCount != 1 || countLog(records, "Validate CAS number", "invalid CAS number") != 1 {
+		t.Errorf("InvalidCasCount = %d, want the invalid CAS number of row 5 logged", summary.InvalidCasCount)
+	}
+	for _, chemical := range fake.chemicals {
+		if chemical.Name == "lithium salt" && (chemical.SafetyInfo.CasNumber != "" || !strings.Contains(chemical.SafetyInfo.SafetyNotes, "1394595-45-5")) {
+			t.Errorf("invalid CAS number sent as %q, notes %q", chemical.SafetyInfo.CasNumber, chemical.SafetyInfo.SafetyNotes)
+		}
+	}
+}
+
+func TestImportMergesSpellingVariants(t *testing.T) {
+	csvFile := writeTestCsv(t, "chemicals-05-20-16-55.csv", []map[string]string{
+		{"Row number": "1", "CIID": "1", "Chemical Name": "1-Butanol"},
+		{"Row number": "2", "CIID": "2", "Chemical Name": "1‐butanol "},
+		{"Row number": "3", "CIID": "3", "Chemical Name": "n-butanol"},
+	})
+
+	fake := newFakePortal(t)
+	summary, _ := runTestImport(t, fake, testOptions(t, fake, csvFile))
+
+	// n-butanol is only reported until an alias merges it
+	if summary.CreatedChemicalCount != 2 || summary.DuplicateReviewCount != 1 {
+		t.Errorf("created %d chemicals with %d groups to review, want 2 and 1", summary.CreatedChemicalCount, summary.DuplicateReviewCount)
+	}
+}
+
+func TestImportRetriesUnavailablePortal(t *testing.T) {
+	csvFile := writeTestCsv(t, "chemicals-05-20-16-55.csv", []map[string]string{
+		{"Row number": "1", "CIID": "1", "Chemical Name": "1-butanol"},
+	})
+
+	fake := newFakePortal(t)
+	fake.unavailablePosts = 2
+	opts := testOptions(t, fake, csvFile)
+	opts.MaxAttempts = 3
+	summary, records := runTestImport(t, fake, opts)
+
+	if summary.ErrorCount != 0 || summary.CreatedChemicalCount != 1 {
+		t.Fatalf("%d errors, %d chemicals created, want the chemical created after the 503s", summary.ErrorCount, summary.CreatedChemicalCount)
+	}
+
+	// the chemical is the first record created, so its log entry took all three attempts
+	attempts := map[string]string{}
+	for _, record := range records[1:] {
+		attempts[record[1]] = record[6]
+	}
+	if attempts["Create new chemical"] != "3" {
+		t.Errorf("logged attempts %v, want 3 for the chemical", attempts)
+	}
+}
+
+func TestImportLogsAttemptsOnlyForPortalCalls(t *testing.T) {
+	csvFile := writeTestCsv(t, "chemicals-05-20-16-55.csv", []map[string]string{
+		{"Row number": "1", "CIID": "1", "Chemical Name": "1-butanol", "Recipe": "99%"},
+	})
+
+	// the chemical lookup is tried twice; the planned creates after it make no call of their own
+	fake := newFakePortal(t)
+	fake.strict = true
+	fake.failingGets = 1
+	opts := testOptions(t, fake, csvFile)
+	opts.NotFound = "strict"
+	opts.Preload = false
+	opts.DryRun = true
+	opts.MaxAttempts = 3
+	summary, records := runTestImport(t, fake, opts)
+
+	if summary.ErrorCount != 0 {
+		t.Fatalf("%d errors, want the lookup to succeed on its second attempt", summary.ErrorCount)
+	}
+	for _, record := range records[1:] {
+		if strings.HasPrefix(record[2], "would ") && record[6] != "" {
+			t.Errorf("%s (%s) logged %s attempts, want none for an entry without a Portal call", record[1], record[2], record[6])
+		}
+	}
+}
+
+func TestImportDoesNotRetryWithOneAttempt(t *testing.T) {
+	csvFile := writeTestCsv(t, "chemicals-05-20-16-55.csv", []map[string]string{
+		{"Row number": "1", "CIID": "1", "Chemical Name": "1-butanol"},
+	})
+
+	fake := newFakePortal(t)
+	fake.unavailablePosts = 1
+	opts := testOptions(t, fake, csvFile)
+	opts.MaxAttempts = 1
+	summary, _ := runTestImport(t, fake, opts)
+
+	if summary.ErrorCount == 0 || summary.CreatedChemicalCount != 0 {
+		t.Errorf("%d errors, %d chemicals created, want the 503 to fail the row", summary.ErrorCount, summary.CreatedChemicalCount)
+	}
+}
+
+func TestImportChecksChemicalsAgainstPreloadedCatalogue(t *testing.T) {
+	fake := newFakePortal(t)
+	opts := testOptions(t, fake, "chemicals-05-20-16-55.csv")
+	runTestImport(t, fake, opts)
+
+	// the second run finds everything in the catalogue instead of looking each row up
+	fake.chemicalLookups = 0
+	opts.LogFile = filepath.Join(t.TempDir(), "log.csv")
+	summary, _ := runTestImport(t, fake, opts)
+
+	if summary.CreatedChemicalCount != 0 || summary.CreatedRecipeCount != 0 {
+		t.Errorf("second run created %d chemicals and %d recipes, want none", summary.CreatedChemicalCount, summary.CreatedRecipeCount)
+	}
+	// besides the -not-found probe, the only lookup is the miss of the CAS number of vanadium (iii) chloride,
+	// which was created by a row without one; the catalogue passes it on to the Portal
+	if fake.chemicalLookups != 2 {
+		t.Errorf("%d chemical and recipe lookups, want all other checks answered by the catalogue", fake.chemicalLookups-1)
+	}
+}
+
+func TestCatalogueChecksMissesAgainstPortal(t *testing.T) {
+	fake := newFakePortal(t)
+	client := fake.client()
+	catalogue, err := LoadCatalogue(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// created behind the back of the catalogue, e.g. by someone else during the run
+	chemicalID, err := client.CreateChemical(portal.PayloadChemical{Name: "Acetone", SafetyInfo: portal.PortalSafetyInfo{CasNumber: "67-64-1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	recipeID, err := client.CreateChemicalRecipe(portal.PayloadChemicalRecipe{ChemicalUUID: uuid.MustParse(chemicalID), Title: "99%"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if found, id, err := catalogue.CheckIfChemicalExists("Acetone"); err != nil || !found || id != chemicalID {
+		t.Errorf("by name: found %v, %q, %v, want the chemical from the Portal", found, id, err)
+	}
+	if found, id, err := catalogue.CheckIfChemicalWithCasExists("67-64-1"); err != nil || !found || id != chemicalID {
+		t.Errorf("by CAS number: found %v, %q, %v, want the chemical from the Portal", found, id, err)
+	}
+	if found, id, err := catalogue.CheckIfChemicalRecipeExists("99%", chemicalID); err != nil || !found || id != recipeID {
+		t.Errorf("recipe: found %v, %q, %v, want the recipe from the Portal", found, id, err)
+	}
+	// exact names, like the Portal: another spelling is not the same chemical
+	if found, _, err := catalogue.CheckIfChemicalExists("acetone"); err != nil || found {
+		t.Errorf("other spelling: found %v, %v, want no chemical", found, err)
+	}
+}
+
+func TestImportWithoutCatalogueLooksUpRows(t *testing.T) {
+	csvFile := writeTestCsv(t, "chemicals-05-20-16-55.csv", []map[string]string{
+		{"Row number": "1", "CIID": "1", "Chemical Name": "acetone"},
+		{"Row number": "2", "CIID": "2", "Chemical Name": "acetone", "Recipe": "99%"},
+	})
+
+	fake := newFakePortal(t)
+	fake.noCatalogue = true
+	summary, _ := runTestImport(t, fake, testOptions(t, fake, csvFile))
+
+	if summary.ErrorCount != 0 || summary.CreatedChemicalCount != 1 {
+		t.Errorf("%d errors, %d chemicals created, want acetone created once", summary.ErrorCount, summary.CreatedChemicalCount)
+	}
+	if fake.chemicalLookups == 0 {
+		t.Errorf("no lookups, want the rows looked up when the catalogue can't be loaded")
+	}
+}
+
+func TestImportWithWorkersMatchesSequentialRun(t *testing.T) {
+	for _, fixture := range importFixtures {
+		t.Run(fixture.csvFile, func(t *testing.T) {
+			fake := newFakePortal(t)
+			want, _ := runTestImport(t, fake, testOptions(t, fake, fixture.csvFile))
+
+			concurrentFake := newFakePortal(t)
+			opts := testOptions(t, concurrentFake, fixture.csvFile)
+			opts.Workers = 8
+			got, records := runTestImport(t, concurrentFake, opts)
+
+			// the rows of a chemical are processed in row order, so the run creates what a run with one worker does
+			got.LogFile, want.LogFile = "", ""
+			if *got != *want {
+				t.Errorf("summary with 8 workers\n%+v\nwant\n%+v", *got, *want)
+			}
+			if concurrentFake.recordCount() != fake.recordCount() {
+				t.Errorf("fake Portal holds %d records, want %d", concurrentFake.recordCount(), fake.recordCount())
+			}
+			for i := 2; i < len(records); i++ {
+				if prev, row := records[i-1][0], records[i][0]; len(row) < len(prev) || (len(row) == len(prev) && row < prev) {
+					t.Fatalf("log line %d is for row %s after row %s, want the log in row order", i+1, row, prev)
+				}
+			}
+		})
+	}
+}
+
+func TestImportWithWorkersCreatesEachChemicalOnce(t *testing.T) {
+	var rows []map[string]string
+	for i := 1; i <= 40; i++ {
+		name := []string{"acetone", "Acetone", "ethanol", "sulfolane"}[i%4]
+		rows = append(rows, map[string]string{"Row number": strconv.Itoa(i), "CIID": strconv.Itoa(i), "Chemical Name": name, "Recipe": "99%", "CAS Number ": ""})
+	}
+	csvFile := writeTestCsv(t, "chemicals-05-20-16-55.csv", rows)
+
+	// without the catalogue every row asks the Portal, so the rows of a chemical have to wait for the one creating it
+	fake := newFakePortal(t)
+	fake.noCatalogue = true
+	opts := testOptions(t, fake, csvFile)
+	opts.Workers = 8
+	summary, _ := runTestImport(t, fake, opts)
+
+	if summary.ErrorCount != 0 || summary.CreatedChemicalCount != 3 || len(fake.chemicals) != 3 {
+		t.Errorf("%d errors, %d chemicals created, fake holds %d, want acetone, ethanol and sulfolane created once",
+			summary.ErrorCount, summary.CreatedChemicalCount, len(fake.chemicals))
+	}
+	if summary.CreatedInstanceCount != 40 {
+		t.Errorf("%d instances created, want 40", summary.CreatedInstanceCount)
+	}
+}
+
+func TestImportAgainstStrictPortal(t *testing.T) {
+	fake := newFakePortal(t)
+	fake.strict = true
+	opts := testOptions(t, fake, "chemicals-05-20-16-55.csv")
+	first, _ := runTestImport(t, fake, opts)
+
+	opts.LogFile = filepath.Join(t.TempDir(), "log.csv")
+	second, _ := runTestImport(t, fake, opts)
+
+	// the probe finds the 404s, so missing records are created and existing ones found
+	if first.CreatedChemicalCount == 0 || first.CheckChemicalErrorCount+first.CheckInstanceErrorCount != 0 {
+		t.Errorf("first run created %d chemicals with %d check errors", first.CreatedChemicalCount, first.CheckChemicalErrorCount+first.CheckInstanceErrorCount)
+	}
+	if second.CreatedChemicalCount+second.CreatedInstanceCount != 0 {
+		t.Errorf("second run created %d chemicals and %d instances, want none", second.CreatedChemicalCount, second.CreatedInstanceCount)
+	}
+}
+
+func TestImportStrictDoesNotCreateOnServerError(t *testing.T) {
+	csvFile := writeTestCsv(t, "chemicals-05-20-16-55.csv", []map[string]string{
+		{"Row number": "1", "CIID": "1", "Chemical Name": "acetone"},
+	})
+
+	for _, mode := range []string{"strict", "legacy"} {
+		t.Run(mode, func(t *testing.T) {
+			fake := newFakePortal(t)
+			fake.strict = mode == "strict"
+			opts := testOptions(t, fake, csvFile)
+			opts.NotFound = mode
+			opts.Preload = false
+			opts.MaxAttempts = 1
+			runTestImport(t, fake, opts)
+
+			// the lookup of the existing chemical fails with a real 500
+			fake.failingGets = 1
+			opts.LogFile = filepath.Join(t.TempDir(), "log.csv")
+			summary, _ := runTestImport(t, fake, opts)
+
+			created := summary.CreatedChemicalCount
+			if mode == "strict" && (created != 0 || summary.CheckChemicalErrorCount != 1) {
+				t.Errorf("created %d chemicals with %d check errors, want the failed lookup to fail the row", created, summary.CheckChemicalErrorCount)
+			}
+			// the legacy convention can't tell the 500 from a missing record - the duplicate strict mode prevents
+			if mode == "legacy" && created != 1 {
+				t.Errorf("created %d chemicals, want the legacy mode to create a duplicate", created)
+			}
+		})
+	}
+}
+
+func TestImportFindsChemicalsWithSpecialCharactersByName(t *testing.T) {
+	names := []string{"ESTANE® AG 8451", "4-(methylthio)benzyl alcohol", "éthyl acetate", "Sodium salt 50% w/w & water"}
+	var rows []map[string]string
+	for i, name := range names {
+		rows = append(rows, map[string]string{"Row number": strconv.Itoa(i + 1), "CIID": strconv.Itoa(i + 1), "Chemical Name": name, "Recipe": "re\u0301agent grade"})
+	}
+	csvFile := writeTestCsv(t, "chemicals-05-20-16-55.csv", rows)
+
+	fake := newFakePortal(t)
+	opts := testOptions(t, fake, csvFile)
+	opts.Preload = false
+	opts.VerifyLookups = true
+	first, records := runTestImport(t, fake, opts)
+
+	if first.CreatedChemicalCount != len(names) || first.UnverifiedLookupCount != 0 {
+		t.Errorf("created %d chemicals, %d not found by name, want %d and none", first.CreatedChemicalCount, first.UnverifiedLookupCount, len(names))
+	}
+	if verified := countLog(records, "Verify chemical lookup", "success"); verified != len(names) {
+		t.Errorf("logged %d verified lookups, want %d", verified, len(names))
+	}
+	for _, chemical := range fake.chemicals {
+		if strings.HasSuffix(chemical.Name, "thyl acetate") && chemical.Name != "\u00e9thyl acetate" {
+			t.Errorf("stored %q, want the accent composed (NFC)", chemical.Name)
+		}
+	}
+
+	// later runs find every chemical by its name and every recipe by its decomposed title,
+	// with the catalogue and without it
+	for _, preload := range []bool{false, true} {
+		opts.Preload = preload
+		opts.LogFile = filepath.Join(t.TempDir(), "log.csv")
+		again, _ := runTestImport(t, fake, opts)
+		if again.CreatedChemicalCount+again.CreatedRecipeCount != 0 {
+			t.Errorf("preload=%t: run created %d chemicals and %d recipes, want none", preload, again.CreatedChemicalCount, again.CreatedRecipeCount)
+		}
+	}
+}
+
+func TestImportWarnsAboutChemicalsNotFoundByName(t *testing.T) {
+	csvFile := writeTestCsv(t, "chemicals-05-20-16-55.csv", []map[string]string{
+		{"Row number": "1", "CIID": "1", "Chemical Name": "ESTANE® AG 8451", "Recipe": "99%"},
+		{"Row number": "2", "CIID": "2", "Chemical Name": "acetone", "Recipe": "99%"},
+	})
+
+	fake := newFakePortal(t)
+	fake.asciiNameLookups = true
+	opts := testOptions(t, fake, csvFile)
+	opts.VerifyLookups = true
+	summary, records := runTestImport(t, fake, opts)
+
+	if summary.UnverifiedLookupCount != 1 || countLog(records, "Verify chemical lookup", "chemical not found by name") != 1 {
+		t.Errorf("%d chemicals not found by name, want the one with ®", summary.UnverifiedLookupCount)
+	}
+	if summary.ErrorCount != 0 || summary.CreatedInstanceCount != 2 {
+		t.Errorf("%d errors and %d instances, want the warning not to fail the row", summary.ErrorCount, summary.CreatedInstanceCount)
+	}
+}
+
+func TestImportReusesChemicalWithoutCasForLaterCasRow(t *testing.T) {
+	csvFile := writeTestCsv(t, "chemicals-05-20-16-55.csv", []map[string]string{
+		{"Row number": "1", "CIID": "1", "Chemical Name": "vanadium (iii) chloride"},
+		{"Row number": "2", "CIID": "2", "Chemical Name": "vanadium (iii) chloride", "CAS Number ": "7718-98-1"},
+		{"Row number": "3", "CIID": "3", "Chemical Name": "vanadium (iii) chloride", "CAS Number ": "7718-98-1"},
+	})
+
+	for _, preload := range []bool{true, false} {
+		t.Run(fmt.Sprintf("preload=%t", preload), func(t *testing.T) {
+			fake := newFakePortal(t)
+			opts := testOptions(t, fake, csvFile)
+			opts.Preload = preload
+			summary, _ := runTestImport(t, fake, opts)
+
+			if summary.ErrorCount != 0 || summary.CreatedChemicalCount != 1 || len(fake.chemicals) != 1 {
+				t.Errorf("%d errors, created %d chemicals, fake holds %d, want the CAS rows to reuse the chemical of row 1",
+					summary.ErrorCount, summary.CreatedChemicalCount, len(fake.chemicals))
+			}
+		})
+	}
+}
+
+func TestImportWithoutCasRouteChecksByName(t *testing.T) {
+	for _, mode := range []string{"legacy", "strict"} {
+		t.Run(mode, func(t *testing.T) {
+			csvFile := writeTestCsv(t, "chemicals-05-20-16-55.csv", []map[string]string{
+				{"Row number": "1", "CIID": "1", "Chemical Name": "vanadium (iii) chloride", "CAS Number ": "7718-98-1"},
+				{"Row number": "2", "CIID": "2", "Chemical Name": "vanadium (iii) chloride", "CAS Number ": "7718-98-1"},
+			})
+
+			fake := newFakePortal(t)
+			fake.noCasRoute = true
+			fake.strict = mode == "strict"
+			opts := testOptions(t, fake, csvFile)
+			opts.NotFound = mode
+			opts.Preload = false
+			summary, _ := runTestImport(t, fake, opts)
+
+			if summary.ErrorCount != 0 || summary.CreatedChemicalCount != 1 {
+				t.Errorf("%d errors, created %d chemicals, want the second row to find the chemical by name", summary.ErrorCount, summary.CreatedChemicalCount)
+			}
+		})
+	}
+}
+
+func TestGroupRowsFollowsSharedKeys(t *testing.T) {
+	csvFile := writeTestCsv(t, "chemicals-05-20-16-55.csv", []map[string]string{
+		{"Row number": "1", "CIID": "1", "Chemical Name": "vanadium (iii) chloride"},
+		{"Row number": "2", "CIID": "2", "Chemical Name": "acetone"},
+		{"Row number": "3", "CIID": "3", "Chemical Name": "vanadium trichloride", "CAS Number ": "7718-98-1"},
+		{"Row number": "4", "CIID": "4", "Chemical Name": "Vanadium (III) chloride", "CAS Number ": "7718-98-1"},
+		{"Row number": "5", "CIID": "2", "Chemical Name": "ethanol"},
+	})
+
+	fake := newFakePortal(t)
+	opts := testOptions(t, fake, csvFile)
+	cols := testColumns(t, opts)
+	if _, err := ApplySheetLayout(cols, opts.LayoutsDir); err != nil {
+		t.Fatal(err)
+	}
+	chemicalNames, err := ResolveChemicalNames(csvFile, cols, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	run := &importRun{cols: cols, chemicalNames: chemicalNames}
+
+	records := readCsv(t, csvFile)
+	var rows []rowJob
+	for i, record := range records[1:] {
+		rows = append(rows, rowJob{rowNum: i + 1, row: record})
+	}
+
+	// row 4 ties row 3 (by CAS number) to row 1 (by name), row 5 shares the CIID of row 2
+	var got [][]int
+	for _, group := range run.groupRows(rows) {
+		var rowNums []int
+		for _, job := range group {
+			rowNums = append(rowNums, job.rowNum)
+		}
+		got = append(got, rowNums)
+	}
+	if want := [][]int{{1, 3, 4}, {2, 5}}; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("groups %v, want %v", got, want)
+	}
+}
+
+func TestBuildChemicalInstancePayloadReadsFormattedCiids(t *testing.T) {
+	cols := NewColumns()
+	cols.Ciid = 0
+	cols.ParentID = 1
+	recipeID := uuid.NewString()
+
+	tests := []struct {
+		ciid, parent   string
+		wantCiid       int64
+		wantParentCiid int64
+		wantErr        bool
+	}{
+		{ciid: "1022", parent: "", wantCiid: 1022},
+		{ciid: "1,022", parent: "1,021", wantCiid: 1022, wantParentCiid: 1021},
+		{ciid: " 1 022 ", parent: " 12,345 ", wantCiid: 1022, wantParentCiid: 12345},
+		{ciid: "1.022", wantErr: true},
+		{ciid: "1022", parent: "n/a", wantErr: true},
+	}
+	for _, tt := range tests {
+		instance, parentCiid, err := buildChemicalInstancePayload([]string{tt.ciid, tt.parent}, cols, recipeID)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("CIID %q, parent %q: want an error", tt.ciid, tt.parent)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("CIID %q, parent %q: %v", tt.ciid, tt.parent, err)
+			continue
+		}
+		if instance.ID != tt.wantCiid || parentCiid != tt.wantParentCiid {
+			t.Errorf("CIID %q, parent %q: got %d and %d, want %d and %d", tt.ciid, tt.parent, instance.ID, parentCiid, tt.wantCiid, tt.wantParentCiid)
+		}
+	}
+}
+
+func TestImportReadsCiidWithThousandsSeparator(t *testing.T) {
+	csvFile := writeTestCsv(t, "chemicals-05-20-16-55.csv", []map[string]string{
+		{"Row number": "1", "CIID": "1,022", "Chemical Name": "acetone", "Recipe": "99%"},
+	})
+
+	fake := newFakePortal(t)
+	summary, _ := runTestImport(t, fake, testOptions(t, fake, csvFile))
+	if summary.ErrorCount != 0 || len(fake.instances) != 1 {
+		t.Fatalf("%d errors, %d instances, want one", summary.ErrorCount, len(fake.instances))
+	}
+	for _, instance := range fake.instances {
+		if instance.ID != 1022 {
+			t.Errorf("instance has CIID %d, want 1022", instance.ID)
+		}
+	}
+
+	summary, _ = runTestImport(t, fake, testOptions(t, fake, csvFile))
+	if summary.ErrorCount != 0 || len(fake.instances) != 1 {
+		t.Errorf("second run: %d errors, %d instances, want the instance found by its CIID", summary.ErrorCount, len(fake.instances))
+	}
+}
+
+func TestImportResumeFinishesHalfCreatedContainers(t *testing.T) {
+	csvFile := writeTestCsv(t, "chemicals-05-20-16-55.csv", []map[string]string{
+		{"Row number": "1", "CIID": "7", "Chemical Name": "acetone", "Recipe": "99%", "amount": "3 x 500 g"},
+	})
+
+	// the second container fails, so the row's CIID is not in the Portal yet
+	fake := newFakePortal(t)
+	fake.failInstanceAt = 2
+	opts := testOptions(t, fake, csvFile)
+	first, _ := runTestImport(t, fake, opts)
+	if first.CreatedInstanceCount != 1 || first.CreateInstanceErrorCount != 1 {
+		t.Fatalf("created %d instances with %d errors, want 1 and 1", first.CreatedInstanceCount, first.CreateInstanceErrorCount)
+	}
+
+	opts.ResumeFrom = opts.LogFile
+	opts.LogFile = filepath.Join(t.TempDir(), "log.csv")
+	resumed, _ := runTestImport(t, fake, opts)
+	if resumed.CreatedInstanceCount != 2 || resumed.ErrorCount != 0 {
+		t.Errorf("resumed run created %d instances with %d errors, want the 2 missing ones", resumed.CreatedInstanceCount, resumed.ErrorCount)
+	}
+
+	labels := map[string]bool{}
+	for _, instance := range fake.instances {
+		labels[instance.Label] = true
+	}
+	if len(fake.instances) != 3 || len(labels) != 3 {
+		t.Errorf("fake Portal holds %d instances with labels %v, want one per container", len(fake.instances), labels)
+	}
+
+	// the row's CIID is created last, so a plain re-run of the finished row creates nothing
+	opts.ResumeFrom = ""
+	opts.LogFile = filepath.Join(t.TempDir(), "log.csv")
+	again, _ := runTestImport(t, fake, opts)
+	if again.CreatedInstanceCount != 0 {
+		t.Errorf("re-run created %d instances, want none", again.CreatedInstanceCount)
+	}
+}
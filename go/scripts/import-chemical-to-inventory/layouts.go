@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/joho/godotenv"
+)
+
+// Layout is a known version of the inventory sheet export, loaded from an env file in the layouts directory.
+// LAYOUT_REQUIRES and LAYOUT_EXCLUDES fingerprint its header row, the COLUMN_* entries are its column mapping.
+type Layout struct {
+	Name     string            // file name without .env, e.g. 2025-05-20
+	File     string            // path of the layout file
+	Requires []string          // normalised headers the export must have
+	Excludes []string          // normalised headers the export must not have
+	Mappings map[string]string // COLUMN_* key -> column letter or header text
+}
+
+// splitLayoutHeaders splits a "|" separated header list - headers can contain commas, e.g. "amount, kg"
+func splitLayoutHeaders(value string) []string {
+	var headers []string
+	for _, header := range strings.Split(value, "|") {
+		if header = normaliseHeader(header); header != "" {
+			headers = append(headers, header)
+		}
+	}
+	return headers
+}
+
+// LoadLayouts reads every *.env file of the layouts directory
+func LoadLayouts(dir string) ([]*Layout, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.env"))
+	if err != nil {
+		return nil, fmt.Errorf("error listing layouts: %w", err)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no layout files (*.env) in %s", dir)
+	}
+	sort.Strings(files)
+
+	var layouts []*Layout
+	for _, file := range files {
+		env, err := godotenv.Read(file)
+		if err != nil {
+			return nil, fmt.Errorf("error loading layout %s: %w", file, err)
+		}
+
+		layout := &Layout{
+			Name:     strings.TrimSuffix(filepath.Base(file), ".env"),
+			File:     file,
+			Requires: splitLayoutHeaders(env["LAYOUT_REQUIRES"]),
+			Excludes: splitLayoutHeaders(env["LAYOUT_EXCLUDES"]),
+			Mappings: map[string]string{},
+		}
+		if len(layout.Requires) == 0 {
+			return nil, fmt.Errorf("layout %s has no LAYOUT_REQUIRES headers to recognise it by", file)
+		}
+		for key, value := range env {
+			if strings.HasPrefix(key, "COLUMN_") {
+				layout.Mappings[key] = value
+			}
+		}
+
+		layouts = append(layouts, layout)
+	}
+
+	return layouts, nil
+}
+
+// Matches reports whether a header row has all the required headers of the layout and none of the excluded ones
+func (l *Layout) Matches(header []string) bool {
+	present := map[string]bool{}
+	for _, text := range header {
+		present[normaliseHeader(text)] = true
+	}
+
+	for _, required := range l.Requires {
+		if !present[required] {
+			return false
+		}
+	}
+	for _, excluded := range l.Excludes {
+		if present[excluded] {
+			return false
+		}
+	}
+	return true
+}
+
+// DetectLayout picks the one layout matching the header row.
+// An export that matches no layout, or more than one, is refused - add or tighten a layout file first.
+func DetectLayout(layouts []*Layout, header []string) (*Layout, error) {
+	var matches []*Layout
+	for _, layout := range layouts {
+		if layout.Matches(header) {
+			matches = append(matches, layout)
+		}
+	}
+
+	if len(matches) == 1 {
+		return matches[0], nil
+	}
+
+	if len(matches) == 0 {
+		var known []string
+		for _, layout := range layouts {
+			known = append(known, layout.Name)
+		}
+		return nil, fmt.Errorf("unknown sheet layout (known layouts: %s) - add a layout file for this export", strings.Join(known, ", "))
+	}
+
+	var names []string
+	for _, layout := range matches {
+		names = append(names, layout.Name)
+	}
+	return nil, fmt.Errorf("sheet layout is ambiguous, it matches layouts %s - tighten their LAYOUT_REQUIRES / LAYOUT_EXCLUDES", strings.Join(names, ", "))
+}
+
+// ApplySheetLayout detects the layout of cols.RawCsv among the layouts in dir and resolves the column mapping for it
+func ApplySheetLayout(cols *Columns, dir string) (*Layout, error) {
+	layouts, err := LoadLayouts(dir)
+	if err != nil {
+		return nil, err
+	}
+	header, err := readCsvHeader(cols.RawCsv)
+	if err != nil {
+		return nil, err
+	}
+	layout, err := DetectLayout(layouts, header)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", cols.RawCsv, err)
+	}
+	fmt.Printf("Sheet layout: %s (%s)\n", layout.Name, layout.File)
+	cols.ApplyLayout(layout)
+	if err := cols.ResolveHeaders(header); err != nil {
+		return nil, err
+	}
+	return layout, nil
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/miru-smart-technologies/Scripts/go/pkg/portal"
+)
+
+// LocationAliases maps the free text of the location column to canonical Portal location names.
+// The aliases are loaded from a user-editable CSV file with an "alias,location" header.
+type LocationAliases struct {
+	aliases map[string]string // normalised alias -> canonical location
+	ordered []string          // aliases sorted longest first, for prefix matching
+}
+
+// normaliseLocationText lowercases a location and collapses all whitespace (including newlines) to single spaces
+func normaliseLocationText(s string) string {
+	return strings.Join(strings.Fields(strings.ToLower(portal.NormaliseText(s))), " ")
+}
+
+// LoadLocationAliases reads the alias file
+func LoadLocationAliases(filename string) (*LocationAliases, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("error opening location alias file: %w", err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.Comment = '#'
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("error reading location alias file: %w", err)
+	}
+
+	la := &LocationAliases{aliases: map[string]string{}}
+	for i, record := range records {
+		if i == 0 {
+			continue // Skip header line
+		}
+		if len(record) != 2 {
+			return nil, fmt.Errorf("line %d of location alias file: expected 2 fields, got %d", i+1, len(record))
+		}
+
+		alias := normaliseLocationText(record[0])
+		location := removeExtraSpace(record[1])
+		if alias == "" || location == "" {
+			return nil, fmt.Errorf("line %d of location alias file: alias and location are required", i+1)
+		}
+		if existing, ok := la.aliases[alias]; ok && existing != location {
+			return nil, fmt.Errorf("line %d of location alias file: alias %q already maps to %q", i+1, record[0], existing)
+		}
+
+		la.aliases[alias] = location
+	}
+
+	for alias := range la.aliases {
+		la.ordered = append(la.ordered, alias)
+	}
+	sort.Slice(la.ordered, func(i, j int) bool {
+		if len(la.ordered[i]) != len(la.ordered[j]) {
+			return len(la.ordered[i]) > len(la.ordered[j])
+		}
+		return la.ordered[i] < la.ordered[j]
+	})
+
+	return la, nil
+}
+
+// Resolve returns the canonical location for a location cell.
+// An exact alias match wins; otherwise the longest alias the text starts with is used,
+// so "FC4 until glovebox" and "FC1; metal 2ethylhexanoates" resolve through the "fc4" and "fc1" aliases.
+func (la *LocationAliases) Resolve(raw string) (string, bool) {
+	text := normaliseLocationText(raw)
+	if text == "" {
+		return "", false
+	}
+
+	if location, ok := la.aliases[text]; ok {
+		return location, true
+	}
+
+	for _, alias := range la.ordered {
+		if !strings.HasPrefix(text, alias) {
+			continue
+		}
+		// only match on a word boundary - "fc1" must not match "fc12"
+		next := text[len(alias)]
+		if next == ' ' || next == ';' || next == ',' || next == ':' || next == '(' || next == '+' || next == '/' {
+			return la.aliases[alias], true
+		}
+	}
+
+	return "", false
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLocationAliasesResolve(t *testing.T) {
+	aliasFile := filepath.Join(t.TempDir(), "location_aliases.csv")
+	content := "# test aliases\n" +
+		"alias,location\n" +
+		"fc1,FC1\n" +
+		"fc 1,FC1\n" +
+		"fc4,FC4\n" +
+		"fc1 fridge,Fridge\n" +
+		"glovebox,Glovebox\n" +
+		"buildbox glovebox,Glovebox\n"
+	if err := os.WriteFile(aliasFile, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	aliases, err := LoadLocationAliases(aliasFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		raw      string
+		location string
+		ok       bool
+	}{
+		// exact matches, ignoring case and whitespace
+		{"FC1", "FC1", true},
+		{" fc  1 ", "FC1", true},
+		{"Buildbox\nGlovebox", "Glovebox", true},
+		// the longest alias the text starts with, on a word boundary
+		{"FC4 until glovebox", "FC4", true},
+		{"FC1; metal 2ethylhexanoates", "FC1", true},
+		{"glovebox (bottom shelf)", "Glovebox", true},
+		{"FC1 fridge, top shelf", "Fridge", true},
+		// unresolved
+		{"fc12", "", false},
+		{"buildbox", "", false},
+		{"fridge", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		location, ok := aliases.Resolve(tt.raw)
+		if location != tt.location || ok != tt.ok {
+			t.Errorf("Resolve(%q) = %q, %v, want %q, %v", tt.raw, location, ok, tt.location, tt.ok)
+		}
+	}
+}
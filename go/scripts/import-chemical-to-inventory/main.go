@@ -2,6 +2,8 @@ package main
 
 import (
 	"encoding/csv"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
 	"os"
@@ -10,384 +12,771 @@ import (
 	"time"
 
 	"github.com/google/uuid"
-	"resty.dev/v3"
+	"github.com/miru-smart-technologies/Scripts/go/pkg/portal"
 )
 
 // --- main function ---
 func main() {
 
-	envFileName := "chemical_inventory.env"
-	csvFilename := "chemicals-05-20-16-55.csv"
+	if len(os.Args) > 1 && os.Args[1] == "rollback" {
+		rollbackOpts, err := ParseRollbackFlags(os.Args[2:])
+		if errors.Is(err, flag.ErrHelp) {
+			return
+		}
+		if err != nil {
+			log.Fatalf("Invalid arguments: %v", err)
+		}
+		if err := rollbackOpts.ApplyMapping(); err != nil {
+			log.Fatalf("Failed to load configuration: %v", err)
+		}
+		client := portal.NewRetryClient(portal.NewPortalClient(portal.Config{BaseURL: rollbackOpts.ApiBaseUrl, Timeout: 30 * time.Second}), portal.DefaultRetryPolicy)
+		if err := RunRollback(rollbackOpts, client); err != nil {
+			log.Fatalf("Rollback failed: %v", err)
+		}
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "review-duplicates" {
+		reviewOpts, err := ParseReviewFlags(os.Args[2:])
+		if errors.Is(err, flag.ErrHelp) {
+			return
+		}
+		if err != nil {
+			log.Fatalf("Invalid arguments: %v", err)
+		}
+		if err := RunReviewDuplicates(reviewOpts); err != nil {
+			log.Fatalf("Review failed: %v", err)
+		}
+		return
+	}
 
-	// 0. load the column mappings
+	if len(os.Args) > 1 && os.Args[1] == "validate-mapping" {
+		validateOpts, err := ParseValidateFlags(os.Args[2:])
+		if errors.Is(err, flag.ErrHelp) {
+			return
+		}
+		if err != nil {
+			log.Fatalf("Invalid arguments: %v", err)
+		}
+		problems, err := RunValidateMapping(validateOpts)
+		if err != nil {
+			log.Fatalf("Validation failed: %v", err)
+		}
+		if problems > 0 {
+			os.Exit(1)
+		}
+		return
+	}
+
+	opts, err := ParseFlags(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		log.Fatalf("Invalid arguments: %v", err)
+	}
+
+	// 0. load the column mappings, API configuration and location aliases
 	cols := NewColumns()
-	if err := cols.LoadFromEnv(envFileName); err != nil {
+	if err := cols.LoadFromEnv(opts.MappingFile); err != nil {
 		log.Fatalf("Failed to load column mappings: %v", err)
 	}
+	if err := cols.ApplyOptions(opts); err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	client := portal.NewPortalClient(portal.Config{BaseURL: cols.ApiBaseUrl, Timeout: opts.Timeout})
+	if err := applyNotFoundMode(client, opts.NotFound); err != nil {
+		log.Fatalf("Failed to find out how the Portal reports missing records: %v - use -not-found strict or legacy", err)
+	}
+
+	if _, err := runImport(opts, cols, client); err != nil {
+		log.Fatalf("Import failed: %v", err)
+	}
+}
+
+// runImport imports the rows of cols.RawCsv into the Portal behind client and returns what it did
+func runImport(opts *Options, cols *Columns, client portal.PortalClient) (*Summary, error) {
+	var err error
+
+	var resumeLog *ResumeLog
+	if opts.ResumeFrom != "" {
+		resumeLog, err = LoadResumeLog(opts.ResumeFrom)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load log to resume from: %w", err)
+		}
+		fmt.Printf("Resuming from %s - %d rows have steps that already succeeded\n", opts.ResumeFrom, resumeLog.RowCount())
+	}
+
+	run := &importRun{
+		cols:      cols,
+		resumeLog: resumeLog,
+		portal:    client,
+		// every Portal call is tried again on transient failures; the log records how many attempts a call took
+		policy: portal.RetryPolicy{
+			MaxAttempts: opts.MaxAttempts,
+			BaseDelay:   portal.DefaultRetryPolicy.BaseDelay,
+			MaxDelay:    portal.DefaultRetryPolicy.MaxDelay,
+		},
+		locks:         NewKeyLocks(),
+		verifyLookups: opts.VerifyLookups,
+		supplierIDs:   map[string]string{},
+		locationIDs:   map[string]string{},
+	}
+
+	if opts.Preload {
+		catalogue, err := LoadCatalogue(portal.NewRetryClient(client, run.policy))
+		if err != nil {
+			fmt.Printf("Could not preload the Portal catalogue: %v - looking up chemicals and recipes row by row\n", err)
+		} else {
+			fmt.Printf("Preloaded the Portal catalogue: %s\n", catalogue)
+			run.catalogue = catalogue
+		}
+	}
+
+	if opts.DryRun {
+		fmt.Println("Dry run - the Portal is only read from, planned actions are written to the plan file")
+		run.planner = NewDryRunClient(client)
+	}
+
+	// the client of the checks before the rows are processed
+	client, _ = run.workerClient()
+
+	fmt.Printf("Importing %s into %s (%s stage)\n", cols.RawCsv, cols.ApiBaseUrl, opts.Stage)
+
+	// pick the column mapping from the layout of the export before reading any rows
+	layout, err := ApplySheetLayout(cols, opts.LayoutsDir)
+	if err != nil {
+		return nil, err
+	}
+
+	run.locationAliases, err = LoadLocationAliases(opts.LocationAliases)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load location aliases: %w", err)
+	}
+
+	// merge the spelling variants of chemical names before anything is created; likely duplicates
+	// that are spelled differently are only reported, see the review-duplicates command
+	chemicalAliases, err := LoadChemicalAliases(opts.ChemicalAliases)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load chemical aliases: %w", err)
+	}
+	run.chemicalNames, err = ResolveChemicalNames(cols.RawCsv, cols, chemicalAliases)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read chemical names: %w", err)
+	}
+	for _, group := range run.chemicalNames.Groups() {
+		if group.NeedsReview() {
+			fmt.Printf("Warning: possible duplicate chemicals %q - run review-duplicates and add an alias to merge them\n", sortedGroupNames(group))
+		}
+	}
+
+	// validate the owners before anything is written, so a typo doesn't leave half the instances without one
+	run.owners, err = ResolveOwners(client, cols.RawCsv, cols, removeExtraSpace(cols.DefaultOwnerUUID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve owners: %w", err)
+	}
+	if run.owners.DefaultID == "" {
+		fmt.Println("Warning: no default owner configured - instances without an owner column value will have no owner")
+	}
 
 	// 1. prepare the processed log file
-	processedLog, err := os.Create("log-" + time.Now().Format("2006-01-02-15-04") + ".csv")
+	processedLog, err := os.Create(opts.LogFile)
 
 	if err != nil {
-		log.Fatalf("failed to create log file: %s", err)
+		return nil, fmt.Errorf("failed to create log file: %w", err)
 
 	}
 	defer processedLog.Close()
 
 	writer := csv.NewWriter(processedLog)
-	defer writer.Flush()
-
 	writer.Write([]string{"FileRowNum",
 		"Type",
 		"Status",
 		"DatabaseID",
 		"ErrorMsg",
-		"ProcessedAt"})
+		"ProcessedAt",
+		"Attempts"})
+	writer.Flush()
 
 	// 2. open the CSV file
 
-	file, err := os.Open(csvFilename)
+	file, err := os.Open(cols.RawCsv)
 	if err != nil {
-		log.Fatalf("failed to open file: %v", err)
+		return nil, fmt.Errorf("failed to open file: %w", err)
 	}
 	defer file.Close()
 
 	reader := csv.NewReader(file)
 	_, err = reader.Read() // Skip header line
 	if err != nil {
-		log.Fatalf("failed to read header: %v", err)
-	}
-
-	// 3. read the CSV file line by line
-	rowNum := 1
-	createdChemicalCount := 0
-	createdRecipeCount := 0
-	chemicalValidationErrorCount := 0
-	emptyRecipeCount := 0
-	checkChemicalErrorCount := 0
-	createChemicalErrorCount := 0
-	missingChemicalIDErrorCount := 0
-	createRecipeErrorCount := 0
-	checkRecipeErrorCount := 0
-	errorCount := 0
-
-	for {
-		fmt.Printf("\rProcessing row %d \n", rowNum)
-		row, err := reader.Read()
-		if err != nil {
-			if err.Error() == "EOF" {
-				break // End of file
-			}
-			fmt.Printf("Error reading row %d: %v - skipping\n", rowNum, err)
-			writeProcessedLog(writer, rowNum, "Read row", "cannot read", "", err.Error())
-			rowNum++
-			errorCount++
-			continue
-		}
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
 
-		fmt.Println("Step 0: Validating required fields")
-		err = checkIfRequiredFieldsPresent("chemical", row, cols)
-		if err != nil {
-			fmt.Printf("Validation error in row %d: %v - skipping\n", rowNum, err)
-			writeProcessedLog(writer, rowNum, "Validate row ", "missing chemical name", "", err.Error())
-			rowNum++
-			errorCount++
-			chemicalValidationErrorCount++
-			continue
-		}
+	// 3. process the rows, in parallel with -workers - the log is still written in row order
+	if opts.Workers > 1 {
+		fmt.Printf("Processing rows with %d workers\n", opts.Workers)
+	}
+	summary, err := run.processRows(reader, processedLog, opts.Workers)
+	if err != nil {
+		return nil, err
+	}
 
-		fmt.Println("Step 1: Processing chemical data and safety info")
+	summary.LogFile = processedLog.Name()
+	summary.DefaultOwner = run.owners.DefaultID
+	summary.Layout = layout.Name
+	summary.DuplicateReviewCount = run.chemicalNames.ReviewCount()
+	if resumeLog != nil {
+		summary.ResumedStepCount = resumeLog.Reused()
+	}
+	summary.Print(opts, cols)
 
-		notes := ""
+	return summary, nil
+}
 
-		if cols.HasColumn(cols.GhsFlammableLiquidCategory) {
-			value, err := cols.GetValueFromRow(row, cols.GhsFlammableLiquidCategory)
-			if err == nil && value != "" {
-				notes = "GHS Flammable liquid category: " + value
-			} else {
-				fmt.Println(err)
-			}
+// processRow imports one row of the CSV through client, writing its log entries to writer and counting them in summary
+func (run *importRun) processRow(client portal.PortalClient, direct portal.PortalClient, writer *LogWriter, summary *Summary, rowNum int, row []string) {
+	cols := run.cols
+	var err error
 
-		}
+	fmt.Printf("\rProcessing row %d \n", rowNum)
 
-		name, err := cols.GetValueFromRow(row, cols.ChemicalName)
-		if err != nil {
-			fmt.Println(err)
-		}
-		cas, _ := cols.GetValueFromRow(row, cols.CasNumber)
-		UNnumber, _ := cols.GetValueFromRow(row, cols.UnNumber)
-		hazardClass, _ := cols.GetValueFromRow(row, cols.HazardClass)
+	fmt.Println("Step 0: Validating required fields")
+	err = checkIfRequiredFieldsPresent("chemical", row, cols)
+	if err != nil {
+		fmt.Printf("Validation error in row %d: %v - skipping\n", rowNum, err)
+		writeProcessedLog(writer.withoutCall(), rowNum, "Validate row ", "missing chemical name", "", err.Error())
+		summary.ErrorCount++
+		summary.ChemicalValidationErrorCount++
+		return
+	}
 
-		pChemical := PayloadChemical{
-			Name: removeExtraSpace(name),
-			SafetyInfo: PortalSafetyInfo{
-				CasNumber:   cas,
-				UNNumber:    UNnumber,
-				HazardClass: hazardClass,
-				SafetyNotes: notes,
-			},
-		}
+	fmt.Println("Step 1: Processing chemical data and safety info")
+
+	ghsHazards, ghsNotes, ghsProblems := buildGhsHazards(row, cols)
+	for _, problem := range ghsProblems {
+		// not fatal - the cell is kept in the safety notes instead
+		fmt.Printf("Warning in row %d: invalid GHS category - %s\n", rowNum, problem)
+		writeProcessedLog(writer.withoutCall(), rowNum, "Validate GHS hazards", "invalid GHS category", "", problem)
+		summary.InvalidGhsCategoryCount++
+	}
+
+	properties, propertyProblems := buildPhysicalProperties(row, cols)
+	for _, problem := range propertyProblems {
+		// not fatal - the property is left unknown
+		fmt.Printf("Warning in row %d: invalid physical property - %s\n", rowNum, problem)
+		writeProcessedLog(writer.withoutCall(), rowNum, "Validate physical properties", "invalid physical property", "", problem)
+		summary.InvalidPropertyCount++
+	}
+
+	name, err := cols.GetValueFromRow(row, cols.ChemicalName)
+	if err != nil {
+		fmt.Println(err)
+	}
+	rawCas, _ := cols.GetValueFromRow(row, cols.CasNumber)
+	cas, casNote, err := parseCasCell(rawCas)
+	if err != nil {
+		// not fatal - the chemical is matched by name instead and the text is kept in the safety notes
+		fmt.Printf("Warning in row %d: invalid CAS number - %v\n", rowNum, err)
+		writeProcessedLog(writer.withoutCall(), rowNum, "Validate CAS number", "invalid CAS number", "", err.Error())
+		summary.InvalidCasCount++
+	}
+	var safetyNotes []string
+	if casNote != "" {
+		safetyNotes = append(safetyNotes, casNote)
+	}
 
-		res, chemicalID, err := checkIfChemicalExistsInDB(pChemical.Name)
+	transport, transportNotes, transportProblems := buildTransport(row, cols)
+	for _, problem := range transportProblems {
+		// not fatal - a value that can't be read is kept in the safety notes instead
+		fmt.Printf("Warning in row %d: invalid transport classification - %s\n", rowNum, problem)
+		writeProcessedLog(writer.withoutCall(), rowNum, "Validate transport classification", "invalid transport classification", "", problem)
+		summary.InvalidTransportCount++
+	}
+	safetyNotes = append(safetyNotes, transportNotes...)
+
+	pChemical := portal.PayloadChemical{
+		Name:            run.chemicalNames.Resolve(name),
+		StateOfMatter:   properties.StateOfMatter,
+		Colour:          properties.Colour,
+		MolecularWeight: properties.MolecularWeight,
+		Density:         properties.Density,
+		SafetyInfo: portal.PortalSafetyInfo{
+			CasNumber:    cas,
+			UNNumber:     transport.UNNumber,
+			HazardClass:  transport.HazardClass,
+			PackingGroup: transport.PackingGroup,
+			SafetyNotes:  strings.Join(append(safetyNotes, ghsNotes...), "; "),
+			GhsHazards:   ghsHazards,
+		},
+	}
+
+	chemicalID, resumed := run.resumeLog.Succeeded(rowNum, "chemical")
+	if resumed {
+		logResumed(run.resumeLog, writer, rowNum, "chemical", pChemical.Name, chemicalID)
+	} else {
+		// the CAS number identifies a chemical whatever it is called in the sheet; the name is used without one,
+		// and with one to find a chemical of that name with no CAS number or the same one, e.g. one an earlier
+		// row without a CAS number created, or one the CAS number lookup can't find
+		var res bool
+		var existingChemicalID string
+		if cas != "" {
+			res, existingChemicalID, err = client.CheckIfChemicalWithCasExists(cas)
+			if errors.Is(err, portal.ErrNoCasLookup) {
+				res, err = false, nil
+			}
+			if err == nil && !res {
+				res, existingChemicalID, err = client.CheckIfChemicalWithNameAndCasExists(pChemical.Name, cas)
+			}
+		} else {
+			res, existingChemicalID, err = client.CheckIfChemicalExists(pChemical.Name)
+		}
 		if err != nil {
 			fmt.Printf("Error checking if chemical exists in DB: %v - skipping\n", err)
 			writeProcessedLog(writer, rowNum, "Check if chemical already exists", "cannot check if chemical exists", "", err.Error())
-			rowNum++
-			errorCount++
-			checkChemicalErrorCount++
-			continue
+			summary.ErrorCount++
+			summary.CheckChemicalErrorCount++
+			return
 		}
 
 		if res {
-			fmt.Printf("Chemical %s already exists in DB - skipping\n", pChemical.Name)
-			writeProcessedLog(writer, rowNum, "Check if chemical already exists", "success", chemicalID, "")
+			logExisting(run.planner, writer, rowNum, "chemical", pChemical.Name, existingChemicalID)
+			chemicalID = existingChemicalID
 		} else {
-			chemicalID, err = createNewChemical(pChemical)
+			chemicalID, err = client.CreateChemical(pChemical)
 			if err != nil {
 				fmt.Printf("Error creating new chemical: %v - skipping\n", err)
 				writeProcessedLog(writer, rowNum, "Create new chemical", "cannot create new chemical", "", err.Error())
-				rowNum++
-				createChemicalErrorCount++
-				errorCount++
-				continue
+				summary.CreateChemicalErrorCount++
+				summary.ErrorCount++
+				return
+			}
+			logCreated(run.planner, writer, rowNum, "chemical", pChemical.Name, chemicalID)
+			summary.CreatedChemicalCount++
+			if run.verifyLookups && run.planner == nil {
+				verifyChemicalLookup(direct, writer, summary, rowNum, pChemical.Name, chemicalID)
 			}
-			fmt.Printf("Created new chemical %s with ID %s\n", pChemical.Name, chemicalID)
-			writeProcessedLog(writer, rowNum, "Create new chemical", "success", chemicalID, "")
-			createdChemicalCount++
 		}
+	}
 
-		fmt.Println("Step 2: Processing chemical recipe data - if there's chem recipe information to process")
+	fmt.Println("Step 2: Processing chemical recipe data - if there's chem recipe information to process")
 
-		err = checkIfRequiredFieldsPresent("recipe", row, cols)
-		if err != nil {
-			fmt.Printf("Recipe title is empty - skipping\n")
-			writeProcessedLog(writer, rowNum, "Validate row", "missing recipe title", "", "recipe title is empty")
-			emptyRecipeCount++
-		} else {
-			// this checmicalID check is cuz sometimes, the check if chemical exist step fails unexpectedly
-			// main hypothesis is due to special character
-			// will look into this later; for now, we will have this chemicalID check
-			if chemicalID == "" {
-				fmt.Printf("Error - chemicalID is empty - skipping\n")
-				writeProcessedLog(writer, rowNum, "Validate chemical ID", "missing chemical ID", "", "no chemical ID available")
-				missingChemicalIDErrorCount++
-				errorCount++
-				continue
-			}
+	recipeID := ""
 
-			recipeTitle, _ := cols.GetValueFromRow(row, cols.RecipeTitle)
-			recipeTitle = removeExtraSpace(recipeTitle)
-			chemicalUUID := uuid.MustParse(chemicalID)
+	err = checkIfRequiredFieldsPresent("recipe", row, cols)
+	if err != nil {
+		fmt.Printf("Recipe title is empty - skipping\n")
+		writeProcessedLog(writer.withoutCall(), rowNum, "Validate row", "missing recipe title", "", "recipe title is empty")
+		summary.EmptyRecipeCount++
+	} else {
+		// this checmicalID check is cuz sometimes, the check if chemical exist step fails unexpectedly
+		// main hypothesis is due to special character
+		// will look into this later; for now, we will have this chemicalID check
+		if chemicalID == "" {
+			fmt.Printf("Error - chemicalID is empty - skipping\n")
+			writeProcessedLog(writer.withoutCall(), rowNum, "Validate chemical ID", "missing chemical ID", "", "no chemical ID available")
+			summary.MissingChemicalIDErrorCount++
+			summary.ErrorCount++
+			return
+		}
 
-			pRecipe := PayloadChemicalRecipe{
-				Title:        recipeTitle,
-				ChemicalUUID: chemicalUUID,
-			}
+		recipeTitle, _ := cols.GetValueFromRow(row, cols.RecipeTitle)
+		recipeTitle = removeExtraSpace(recipeTitle)
+		chemicalUUID := uuid.MustParse(chemicalID)
 
-			// fmt.Printf("double check payload info as right now the check won't work any ways - title: %s\n", pRecipe.Title)
-			// fmt.Printf("double check  chemicalID: %s\n", pRecipe.ChemicalUUID)
-			// fmt.Printf("ChemicalUUID type: %T\n", pRecipe.ChemicalUUID)
+		pRecipe := portal.PayloadChemicalRecipe{
+			Title:        recipeTitle,
+			ChemicalUUID: chemicalUUID,
+		}
 
-			res, recipeID, err := checkIfChemicalRecipeExistsInDB(pRecipe.Title, chemicalID)
+		if resumedRecipeID, ok := run.resumeLog.Succeeded(rowNum, "chemical recipe"); ok {
+			logResumed(run.resumeLog, writer, rowNum, "chemical recipe", pRecipe.Title, resumedRecipeID)
+			recipeID = resumedRecipeID
+		} else {
+			res, existingRecipeID, err := client.CheckIfChemicalRecipeExists(pRecipe.Title, chemicalID)
 			if err != nil {
 				fmt.Printf("Error checking if chemical recipe exists in DB: %v - skipping\n", err)
 				writeProcessedLog(writer, rowNum, "Check if chemical recipe already exists", "cannot check if chemical recipe exists", "", err.Error())
-				rowNum++
-				errorCount++
-				checkRecipeErrorCount++
-				continue
+				summary.ErrorCount++
+				summary.CheckRecipeErrorCount++
+				return
 			}
 
 			if res {
-				fmt.Printf("Chemical recipe %s already exists in DB - skipping\n", pRecipe.Title)
-				writeProcessedLog(writer, rowNum, "Check if chemical recipe already exists", "success", recipeID, "")
+				logExisting(run.planner, writer, rowNum, "chemical recipe", pRecipe.Title, existingRecipeID)
+				recipeID = existingRecipeID
 			} else {
-				recipeID, err := createNewChemicalRecipe(pRecipe)
+				recipeID, err = client.CreateChemicalRecipe(pRecipe)
 				if err != nil {
 					fmt.Printf("Error creating new chemical recipe: %v - skipping\n", err)
 					writeProcessedLog(writer, rowNum, "Create new chemical recipe", "cannot create new chemical recipe", "", err.Error())
-					createRecipeErrorCount++
-					errorCount++
-					rowNum++
-					continue
+					summary.CreateRecipeErrorCount++
+					summary.ErrorCount++
+					return
 				}
-				fmt.Printf("Created new chemical recipe %s with ID %s\n", pRecipe.Title, recipeID)
-				writeProcessedLog(writer, rowNum, "Create new chemical recipe", "success", recipeID, "")
-				createdRecipeCount++
+				logCreated(run.planner, writer, rowNum, "chemical recipe", pRecipe.Title, recipeID)
+				summary.CreatedRecipeCount++
 			}
 		}
+	}
 
-		// UPDATE MODEL;
-		// TODO -3. create owner
-		// we might just use a default owner for now
-
-		// TODO -4. create home locations
+	fmt.Println("Step 3: Processing supplier data")
 
-		// check if location already exists
+	supplierName, supplierCacheKey := normaliseSupplierName(cols.GetOptionalValueFromRow(row, cols.SupplierName, ""))
+	supplierID, cached := run.supplierID(supplierCacheKey)
+	unlockSupplier := func() {}
+	if supplierCacheKey != "" && !cached {
+		// the first row of a supplier looks it up or creates it, the others wait and reuse its ID
+		unlockSupplier = run.locks.Lock("supplier " + supplierCacheKey)
+		defer unlockSupplier()
+		supplierID, cached = run.supplierID(supplierCacheKey)
+	}
 
-		// TODO -5. create supplier
+	if supplierCacheKey == "" {
+		fmt.Printf("Supplier name is empty - skipping\n")
+		summary.EmptySupplierCount++
+	} else if cached {
+		logExisting(run.planner, writer.withoutCall(), rowNum, "supplier", supplierName, supplierID)
+	} else if resumedSupplierID, ok := run.resumeLog.Succeeded(rowNum, "supplier"); ok {
+		logResumed(run.resumeLog, writer, rowNum, "supplier", supplierName, resumedSupplierID)
+		supplierID = resumedSupplierID
+		run.setSupplierID(supplierCacheKey, supplierID)
+	} else {
+		res, existingSupplierID, err := client.CheckIfSupplierExists(supplierName)
+		if err != nil {
+			fmt.Printf("Error checking if supplier exists in DB: %v - skipping\n", err)
+			writeProcessedLog(writer, rowNum, "Check if supplier already exists", "cannot check if supplier exists", "", err.Error())
+			summary.ErrorCount++
+			summary.CheckSupplierErrorCount++
+			return
+		}
 
-		// check if supplier with the same name already exists
+		if res {
+			logExisting(run.planner, writer, rowNum, "supplier", supplierName, existingSupplierID)
+			supplierID = existingSupplierID
+		} else {
+			supplierID, err = client.CreateSupplier(portal.PayloadSupplier{Name: supplierName})
+			if err != nil {
+				fmt.Printf("Error creating new supplier: %v - skipping\n", err)
+				writeProcessedLog(writer, rowNum, "Create new supplier", "cannot create new supplier", "", err.Error())
+				summary.CreateSupplierErrorCount++
+				summary.ErrorCount++
+				return
+			}
+			logCreated(run.planner, writer, rowNum, "supplier", supplierName, supplierID)
+			summary.CreatedSupplierCount++
+		}
+		run.setSupplierID(supplierCacheKey, supplierID)
+	}
 
-		// TODO - 6. Create instance
+	unlockSupplier()
 
-		// check if chemical with the same CIID already exists
-		// if not, check if there's a parentID....
+	fmt.Println("Step 4: Processing location data")
 
-		// payloadChemicalInstance := PayloadChemicalInstance{
-		// 	ID:               int64(row[0]), // B
-		// 	RecipeUUID:       uuid.MustParse(recipeID),
-		// 	Amount:           float64(row[10]), // L
-		// 	// Owner:         TBD
-		// 	Components:       []PortalComponentInstance{},
-		// 	// HomeLocationUUID: uuid.MustParse(row[12]), // M
-		// 	// SupplierUUID:     uuid.MustParse(row[13]), // H
-		// 	// ParentUUID:       uuid.MustParse(row[14]), // ....
-		// 	ManufactureDate:  row[15],
-		// 	ExpirationDate:   row[16], // V
-		// 	LotNumber:        row[17], // J
-		// }
+	locationID := ""
+	locationText := removeExtraSpace(cols.GetOptionalValueFromRow(row, cols.LocationName, ""))
+	locationName, resolved := run.locationAliases.Resolve(locationText)
+	unlockLocation := func() {}
+	if _, cached := run.locationID(locationName); locationText != "" && resolved && !cached {
+		unlockLocation = run.locks.Lock("location " + locationName)
+		defer unlockLocation()
+	}
 
-		rowNum++
+	if locationText == "" {
+		fmt.Printf("Location is empty - skipping\n")
+		summary.EmptyLocationCount++
+	} else if !resolved {
+		// not an error - the instance is still created, just without a home location
+		fmt.Printf("Location %q has no alias - creating instance without location\n", locationText)
+		writeProcessedLog(writer.withoutCall(), rowNum, "Resolve location", "unresolved location", "", "no alias for location "+strconv.Quote(locationText))
+		summary.UnresolvedLocationCount++
+	} else if cachedID, ok := run.locationID(locationName); ok {
+		logExisting(run.planner, writer.withoutCall(), rowNum, "location", locationName, cachedID)
+		locationID = cachedID
+	} else if resumedLocationID, ok := run.resumeLog.Succeeded(rowNum, "location"); ok {
+		logResumed(run.resumeLog, writer, rowNum, "location", locationName, resumedLocationID)
+		locationID = resumedLocationID
+		run.setLocationID(locationName, locationID)
+	} else {
+		res, existingLocationID, err := client.CheckIfLocationExists(locationName)
+		if err != nil {
+			fmt.Printf("Error checking if location exists in DB: %v - skipping\n", err)
+			writeProcessedLog(writer, rowNum, "Check if location already exists", "cannot check if location exists", "", err.Error())
+			summary.ErrorCount++
+			summary.CheckLocationErrorCount++
+			return
+		}
 
+		if res {
+			logExisting(run.planner, writer, rowNum, "location", locationName, existingLocationID)
+			locationID = existingLocationID
+		} else {
+			locationID, err = client.CreateLocation(portal.PayloadLocation{Name: locationName})
+			if err != nil {
+				fmt.Printf("Error creating new location: %v - skipping\n", err)
+				writeProcessedLog(writer, rowNum, "Create new location", "cannot create new location", "", err.Error())
+				summary.CreateLocationErrorCount++
+				summary.ErrorCount++
+				return
+			}
+			logCreated(run.planner, writer, rowNum, "location", locationName, locationID)
+			summary.CreatedLocationCount++
+		}
+		run.setLocationID(locationName, locationID)
 	}
 
-	fmt.Println()
+	unlockLocation()
 
-	fmt.Println("\n=== Processing Summary ===")
-	fmt.Printf("Log file created:              %s\n", processedLog.Name())
-	fmt.Printf("Total rows processed:          %d\n", rowNum)
-	fmt.Printf("Chemicals created:             %d\n", createdChemicalCount)
-	fmt.Printf("Chemical recipes created:      %d\n", createdRecipeCount)
-	fmt.Printf("Empty recipe rows:             %d\n", emptyRecipeCount)
+	fmt.Println("Step 5: Processing chemical instance data")
 
-	fmt.Println("\n=== Error Summary ===")
-	fmt.Printf("Total errors:                        %d\n", errorCount)
-	fmt.Printf("Breakdown:\n")
-	fmt.Printf("\t- Missing chemical name errors:    %d\n", chemicalValidationErrorCount)
-	fmt.Printf("\t- Check chemical errors:           %d\n", checkChemicalErrorCount)
-	fmt.Printf("\t- Create chemical errors:          %d\n", createChemicalErrorCount)
-	fmt.Printf("\t- Missing chemical ID errors:      %d\n", missingChemicalIDErrorCount)
-	fmt.Printf("\t- Check recipe errors:             %d\n", checkRecipeErrorCount)
-	fmt.Printf("\t- Create recipe errors:            %d\n", createRecipeErrorCount)
+	// an instance can only be attached to a recipe, so rows without one are left for later
+	if recipeID == "" {
+		fmt.Printf("No recipe available for instance - skipping\n")
+		writeProcessedLog(writer.withoutCall(), rowNum, "Validate recipe ID", "missing recipe ID", "", "no recipe ID available for instance")
+		summary.InstanceWithoutRecipeCount++
+		return
+	}
 
-	fmt.Println("\n=== Consistency Check ===")
-	fmt.Printf("Is total error count correct?  %t\n",
-		errorCount == (chemicalValidationErrorCount+
-			checkChemicalErrorCount+
-			createChemicalErrorCount+
-			missingChemicalIDErrorCount+
-			checkRecipeErrorCount+
-			createRecipeErrorCount),
-	)
+	err = checkIfRequiredFieldsPresent("instance", row, cols)
+	if err != nil {
+		fmt.Printf("Validation error in row %d: %v - skipping\n", rowNum, err)
+		writeProcessedLog(writer.withoutCall(), rowNum, "Validate instance", "missing CIID", "", err.Error())
+		summary.ErrorCount++
+		summary.InstanceValidationErrorCount++
+		return
+	}
 
-}
+	pInstance, parentCiid, err := buildChemicalInstancePayload(row, cols, recipeID)
+	if err != nil {
+		fmt.Printf("Validation error in row %d: %v - skipping\n", rowNum, err)
+		writeProcessedLog(writer.withoutCall(), rowNum, "Validate instance", "invalid instance data", "", err.Error())
+		summary.ErrorCount++
+		summary.InstanceValidationErrorCount++
+		return
+	}
 
-// --- helper functions ---
+	var instanceNotes []string
+	containers, err := containerAmounts(row, cols, properties.Density)
+	if err != nil {
+		// not fatal - the instance is created without an amount and the text is kept in its notes
+		fmt.Printf("Warning in row %d: %v\n", rowNum, err)
+		writeProcessedLog(writer.withoutCall(), rowNum, "Validate amount", "unparseable amount", "", err.Error())
+		summary.UnparseableAmountCount++
+		if containers[0].Unit == "" {
+			instanceNotes = append(instanceNotes, "amount: "+strings.Join(strings.Fields(cols.GetOptionalValueFromRow(row, cols.Amount, "")), " "))
+		}
+	}
 
-func removeExtraSpace(s string) string {
-	s = strings.TrimRight(s, " ")
-	s = strings.TrimLeft(s, " ")
-	return s
-}
+	// dates that can't be read are not sent, the text is kept in the notes instead
+	expiry, manufacture, dateNotes, dateProblems := buildInstanceDates(row, cols)
+	for _, problem := range dateProblems {
+		fmt.Printf("Warning in row %d: %s\n", rowNum, problem)
+		writeProcessedLog(writer.withoutCall(), rowNum, "Validate dates", "invalid date", "", problem)
+		summary.InvalidDateCount++
+	}
+	pInstance.ExpirationDate = expiry
+	pInstance.ManufactureDate = manufacture
+	pInstance.Notes = strings.Join(append(instanceNotes, dateNotes...), "; ")
 
-var client = resty.New().SetBaseURL("http://192.168.2.2:8092")
+	if supplierID != "" {
+		pInstance.SupplierUUID = uuid.MustParse(supplierID)
+	}
 
-func checkIfChemicalExistsInDB(name string) (bool, string, error) {
-	var result PortalChemical
+	if ownerID := run.owners.OwnerFor(cols.GetOptionalValueFromRow(row, cols.Owner, "")); ownerID != "" {
+		pInstance.Owner = uuid.MustParse(ownerID)
+	}
 
-	resp, err := client.R().
-		SetQueryParam("name", name).
-		SetResult(&result).
-		Get("/chemicals/name")
+	if locationID != "" {
+		pInstance.HomeLocationUUID = uuid.MustParse(locationID)
+	}
 
+	if resumedInstanceID, ok := run.resumeLog.Succeeded(rowNum, "chemical instance"); ok {
+		logResumed(run.resumeLog, writer, rowNum, "chemical instance", strconv.FormatInt(pInstance.ID, 10), resumedInstanceID)
+		return
+	}
+
+	res, instanceID, err := client.CheckIfChemicalInstanceExists(pInstance.ID)
 	if err != nil {
-		return false, "", fmt.Errorf("failed to check if chemical exists: %w", err)
+		fmt.Printf("Error checking if chemical instance exists in DB: %v - skipping\n", err)
+		writeProcessedLog(writer, rowNum, "Check if chemical instance already exists", "cannot check if chemical instance exists", "", err.Error())
+		summary.ErrorCount++
+		summary.CheckInstanceErrorCount++
+		return
 	}
 
-	// TODO: we will update the API to return 404 if chemical not found
-	if resp.StatusCode() == 500 {
-		return false, "", nil
+	if res {
+		logExisting(run.planner, writer, rowNum, "chemical instance", strconv.FormatInt(pInstance.ID, 10), instanceID)
+		return
 	}
 
-	if resp.StatusCode() == 200 {
-		return true, result.ID, nil
+	// a container split from another one points at its parent by CIID, which has to be in the DB already
+	if parentCiid != 0 {
+		res, parentID, err := client.CheckIfChemicalInstanceExists(parentCiid)
+		if err == nil && !res {
+			err = fmt.Errorf("parent instance with CIID %d not found", parentCiid)
+		}
+		if err != nil {
+			fmt.Printf("Error resolving parent instance: %v - skipping\n", err)
+			writeProcessedLog(writer, rowNum, "Check parent chemical instance", "cannot find parent chemical instance", "", err.Error())
+			summary.ErrorCount++
+			summary.MissingParentInstanceErrorCount++
+			return
+		}
+		pInstance.ParentUUID = uuid.MustParse(parentID)
 	}
 
-	return false, "", fmt.Errorf("unexpected response code: %d, body: %s", resp.StatusCode(), resp.String())
+	// one instance per container - the first one gets the row's CIID, the Portal numbers the others.
+	// The first one is created last, so a row whose CIID is in the Portal has all its containers; each container
+	// is logged on its own, so resuming a row that failed halfway only creates the containers still missing.
+	label := pInstance.Label
+	ciid := strconv.FormatInt(pInstance.ID, 10)
+	for _, i := range containerOrder(len(containers)) {
+		kind := containerKind(i)
+		if resumedInstanceID, ok := run.resumeLog.Succeeded(rowNum, kind); ok {
+			logResumed(run.resumeLog, writer, rowNum, kind, ciid, resumedInstanceID)
+			continue
+		}
+
+		pContainer := pInstance
+		pContainer.Amount = containers[i].Value
+		pContainer.Unit = containers[i].Unit
+		if len(containers) > 1 {
+			pContainer.Label = strings.TrimSpace(fmt.Sprintf("%s (container %d of %d)", label, i+1, len(containers)))
+		}
+		if i > 0 {
+			pContainer.ID = 0
+		}
+
+		instanceID, err = client.CreateChemicalInstance(pContainer)
+		if err != nil {
+			fmt.Printf("Error creating new chemical instance: %v - skipping the rest of the row, resume from this log to create it\n", err)
+			writeProcessedLog(writer, rowNum, "Create new "+kind, "cannot create new chemical instance", "", err.Error())
+			summary.CreateInstanceErrorCount++
+			summary.ErrorCount++
+			break
+		}
+		logCreated(run.planner, writer, rowNum, kind, ciid, instanceID)
+		summary.CreatedInstanceCount++
+	}
 }
 
-func checkIfChemicalRecipeExistsInDB(name string, chemicalID string) (bool, string, error) {
-	/**
-	 * the reason why we are checking by looking up all the recipes given a chemical ID and see if title matches
-	 * is cuz we don't have a GET API to check if a recipe exists by recipe name and chemical ID
-	 */
-	var result []PortalChemicalRecipe
+// --- helper functions ---
 
-	resp, err := client.R().
-		SetResult(&result).
-		Get("/chemicals/" + chemicalID + "/recipes")
+// applyNotFoundMode sets how client reads the lookups of missing records: strict (404), legacy (500),
+// or auto to ask the Portal before the run
+func applyNotFoundMode(client *portal.RestyClient, mode string) error {
+	if mode != NotFoundAuto {
+		notFound, err := portal.ParseNotFoundMode(mode)
+		if err != nil {
+			return err
+		}
+		client.SetNotFoundMode(notFound)
+		fmt.Printf("Missing records: %s\n", notFoundDescription(notFound))
+		return nil
+	}
 
+	notFound, err := client.ProbeNotFoundMode()
 	if err != nil {
-		return false, "", fmt.Errorf("failed to check if chemical recipe exists: %w", err)
+		return err
 	}
+	client.SetNotFoundMode(notFound)
+	fmt.Printf("Missing records: %s (detected)\n", notFoundDescription(notFound))
+	return nil
+}
 
-	if resp.StatusCode() == 500 {
-		return false, "", nil
+func notFoundDescription(mode portal.NotFoundMode) string {
+	if mode == portal.NotFoundStrict {
+		return "strict - the Portal answers 404, a 500 is an error"
 	}
+	return "legacy - the Portal answers 500, which can't be told apart from a server error"
+}
 
-	if resp.StatusCode() == 200 {
-		for _, recipe := range result {
-			if recipe.Title == name {
-				return true, recipe.ID, nil
-			}
-		}
-		return false, "", nil
+// verifyChemicalLookup looks a chemical that was just created up by its name again, on the Portal itself rather than
+// the catalogue. A chemical the lookup doesn't find, e.g. because its name has characters the Portal decodes
+// differently, would be created again by the next run, so it is logged as a warning.
+func verifyChemicalLookup(client portal.PortalClient, writer *LogWriter, summary *Summary, rowNum int, name string, id string) {
+	found, foundID, err := client.CheckIfChemicalExists(name)
+	switch {
+	case err != nil:
+		err = fmt.Errorf("looking up chemical %q by name failed: %w", name, err)
+	case !found:
+		err = fmt.Errorf("chemical %q is not found by its name", name)
+	case !strings.EqualFold(foundID, id):
+		err = fmt.Errorf("looking up chemical %q by name finds %s instead", name, foundID)
 	}
-
-	return false, "", fmt.Errorf("unexpected response code: %d, body: %s", resp.StatusCode(), resp.String())
+	if err != nil {
+		fmt.Printf("Warning: %v - a later run would not find the chemical it created\n", err)
+		writeProcessedLog(writer, rowNum, "Verify chemical lookup", "chemical not found by name", id, err.Error())
+		summary.UnverifiedLookupCount++
+		return
+	}
+	writeProcessedLog(writer, rowNum, "Verify chemical lookup", "success", id, "")
 }
 
-func createNewChemical(pChemical PayloadChemical) (string, error) {
-	var result PortalChemical
+func removeExtraSpace(s string) string {
+	s = strings.TrimRight(s, " ")
+	s = strings.TrimLeft(s, " ")
+	return s
+}
 
-	resp, err := client.R().
-		SetHeader("Content-Type", "application/json").
-		SetBody(pChemical).
-		SetResult(&result).
-		Post("/chemicals")
+// ciidText returns a CIID cell without the thousands separators and spaces a sheet formats it with, e.g. "1,022"
+func ciidText(value string) string {
+	return strings.NewReplacer(",", "", " ", "").Replace(value)
+}
 
-	if err != nil {
-		return "", fmt.Errorf("failed to create new chemical: %w", err)
+// containerOrder returns the order the containers of a row are created in: the first one, with the row's CIID, last
+func containerOrder(count int) []int {
+	order := make([]int, 0, count)
+	for i := 1; i < count; i++ {
+		order = append(order, i)
 	}
+	return append(order, 0)
+}
 
-	if resp.StatusCode() == 201 {
-		return result.ID, nil
+// containerKind is the record kind the processed log records container i of a row under:
+// "chemical instance" for the first one, "chemical instance container 2" for the second, and so on
+func containerKind(i int) string {
+	if i == 0 {
+		return "chemical instance"
 	}
-
-	return "", fmt.Errorf("failed to create new chemical, status code: %d, response: %s",
-		resp.StatusCode(), resp.String())
+	return fmt.Sprintf("chemical instance container %d", i+1)
 }
 
-func createNewChemicalRecipe(pRecipe PayloadChemicalRecipe) (string, error) {
-	var result PortalChemicalRecipe
-
-	resp, err := client.R().
-		SetHeader("Content-Type", "application/json").
-		SetBody(pRecipe).
-		SetResult(&result).
-		Post("/recipes/")
+// recordKind returns the kind of record a kind of the processed log stands for, without the container
+func recordKind(kind string) string {
+	if base, _, ok := strings.Cut(kind, " container "); ok {
+		return base
+	}
+	return kind
+}
 
+// buildChemicalInstancePayload maps the instance columns of a row onto a payload linked to recipeID.
+// The parent CIID is returned separately since it still has to be resolved to a UUID.
+func buildChemicalInstancePayload(row []string, cols *Columns, recipeID string) (portal.PayloadChemicalInstance, int64, error) {
+	ciidValue, _ := cols.GetValueFromRow(row, cols.Ciid)
+	ciid, err := strconv.ParseInt(ciidText(ciidValue), 10, 64)
 	if err != nil {
-		return "", fmt.Errorf("failed to create new chemical recipe: %w", err)
+		return portal.PayloadChemicalInstance{}, 0, fmt.Errorf("invalid CIID %q", ciidValue)
 	}
 
-	if resp.StatusCode() == 201 {
-		return result.ID, nil
+	var parentCiid int64
+	parentValue := removeExtraSpace(cols.GetOptionalValueFromRow(row, cols.ParentID, ""))
+	if parentValue != "" {
+		parentCiid, err = strconv.ParseInt(ciidText(parentValue), 10, 64)
+		if err != nil {
+			return portal.PayloadChemicalInstance{}, 0, fmt.Errorf("invalid parent ID %q", parentValue)
+		}
+	}
+
+	pInstance := portal.PayloadChemicalInstance{
+		ID:         ciid,
+		RecipeUUID: uuid.MustParse(recipeID),
+		Components: []portal.PortalComponentInstance{},
+		LotNumber:  removeExtraSpace(cols.GetOptionalValueFromRow(row, cols.LotNumber, "")),
+		Label:      removeExtraSpace(cols.GetOptionalValueFromRow(row, cols.Label, "")),
 	}
 
-	return "", fmt.Errorf("failed to create new chemical recipe, status code: %d, response: %s",
-		resp.StatusCode(), resp.String())
+	return pInstance, parentCiid, nil
 }
 
 func checkIfRequiredFieldsPresent(recordType string, row []string, cols *Columns) error {
@@ -415,10 +804,35 @@ func checkIfRequiredFieldsPresent(recordType string, row []string, cols *Columns
 		return nil
 	}
 
+	if recordType == "instance" {
+		ciid, err := cols.GetValueFromRow(row, cols.Ciid)
+		if err != nil {
+			return err
+		}
+		if removeExtraSpace(ciid) == "" {
+			return fmt.Errorf("missing CIID")
+		}
+
+		return nil
+	}
+
 	return fmt.Errorf("unknown record type")
 }
 
-func writeProcessedLog(writer *csv.Writer,
+// LogWriter writes the entries of a processed log. With a retrier, each entry records the attempts
+// of the retrier's last call - the call the entry is about.
+type LogWriter struct {
+	*csv.Writer
+	retrier *portal.RetryClient
+}
+
+// withoutCall returns a writer for entries that made no Portal call, e.g. validations and cached records,
+// so they don't report the attempts of an earlier call no entry was about
+func (w *LogWriter) withoutCall() *LogWriter {
+	return &LogWriter{Writer: w.Writer}
+}
+
+func writeProcessedLog(writer *LogWriter,
 	fileRowNum int,
 	recordType string,
 	status string,
@@ -433,6 +847,14 @@ func writeProcessedLog(writer *csv.Writer,
 		ErrorMsg:    errorMsg,
 		ProcessedAt: time.Now(),
 	}
+	if writer.retrier != nil {
+		entry.Attempts = writer.retrier.TakeAttempts()
+	}
+
+	attempts := ""
+	if entry.Attempts > 0 {
+		attempts = strconv.Itoa(entry.Attempts)
+	}
 
 	writer.Write([]string{
 		strconv.Itoa(entry.FileRowNum),
@@ -441,124 +863,6 @@ func writeProcessedLog(writer *csv.Writer,
 		entry.DatabaseID,
 		entry.ErrorMsg,
 		entry.ProcessedAt.Format(time.RFC3339),
+		attempts,
 	})
 }
-
-// ---- old code ----
-
-// func checkIfChemicalExistsInDB(name string) (bool, string, error) {
-// 	apiURL := "http://192.168.2.2:8092/chemicals/name?name=" + url.PathEscape(name)
-// 	resp, err := http.Get(apiURL)
-
-// 	if err != nil {
-// 		return false, "", fmt.Errorf("failed to check if chemical exists: %w", err)
-// 	}
-
-// 	defer resp.Body.Close()
-// 	bodyBytes, _ := io.ReadAll(resp.Body)
-// 	bodyStr := string(bodyBytes)
-
-// 	// TODO: we will update the API to return 404 if chemical not found
-// 	if resp.StatusCode == http.StatusInternalServerError {
-// 		return false, "", nil
-// 	}
-
-// 	if resp.StatusCode == http.StatusOK {
-// 		var result PortalChemical
-// 		if err := json.Unmarshal(bodyBytes, &result); err != nil {
-// 			return false, "", fmt.Errorf("failed to decode response: %w", err)
-// 		}
-// 		return true, result.ID, nil
-// 	}
-
-// 	return false, "", fmt.Errorf("unexpected response code: %d, body: %s", resp.StatusCode, bodyStr)
-// }
-
-// func checkIfChemicalRecipeExistsInDB(name string, chemicalID string) (bool, string, error) {
-
-// 	/**
-// 	the reason why we are checking by looking up all the recipes given a chemical ID and see if title matches
-// 	is cuz we don't have a GET API to check if a recipe exists by recipe name and chemical ID
-// 	*/
-
-// 	apiURL := "http://192.168.2.2:8092/chemicals/" + chemicalID + "/recipes/"
-// 	resp, err := http.Get(apiURL)
-// 	if err != nil {
-// 		return false, "", fmt.Errorf("failed to check if chemical recipe exists: %w", err)
-// 	}
-// 	defer resp.Body.Close()
-// 	bodyBytes, _ := io.ReadAll(resp.Body)
-// 	bodyStr := string(bodyBytes)
-// 	if resp.StatusCode == http.StatusInternalServerError {
-// 		return false, "", nil
-// 	}
-// 	if resp.StatusCode == http.StatusOK {
-// 		var result []PortalChemicalRecipe
-// 		if err := json.Unmarshal(bodyBytes, &result); err != nil {
-// 			return false, "", fmt.Errorf("failed to decode response: %w", err)
-// 		}
-// 		for _, recipe := range result {
-// 			if recipe.Title == name {
-// 				return true, recipe.ID, nil
-// 			}
-// 		}
-// 		return false, "", nil
-// 	}
-// 	return false, "", fmt.Errorf("unexpected response code: %d, body: %s", resp.StatusCode, bodyStr)
-// }
-
-// func createNewChemical(pChemical PayloadChemical) (string, error) {
-// 	payload, err := json.Marshal(pChemical)
-// 	if err != nil {
-// 		return "", fmt.Errorf("failed to marshal chemical data: %w", err)
-// 	}
-
-// 	apiURL := "http://192.168.2.2:8092/chemicals"
-
-// 	resp, err := http.Post(apiURL, "application/json", bytes.NewBuffer(payload))
-
-// 	if err != nil {
-// 		return "", fmt.Errorf("failed to create new chemical: %w", err)
-// 	}
-// 	defer resp.Body.Close()
-
-// 	if resp.StatusCode == http.StatusCreated {
-// 		var result PortalChemical
-// 		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-// 			return "", fmt.Errorf("failed to decode response: %w", err)
-// 		}
-// 		return result.ID, nil
-// 	}
-
-// 	bodyBytes, _ := io.ReadAll(resp.Body)
-// 	return "", fmt.Errorf("failed to create new chemical, status code: %d, response: %s", resp.StatusCode, string(bodyBytes))
-// }
-
-// func createNewChemicalRecipe(pRecipe PayloadChemicalRecipe) (string, error) {
-// 	payload, err := json.Marshal(pRecipe)
-
-// 	if err != nil {
-// 		return "", fmt.Errorf("failed to marshal chemical recipe data: %w", err)
-// 	}
-
-// 	apiURL := "http://192.168.2.2:8092/recipes/"
-
-// 	resp, err := http.Post(apiURL, "application/json", bytes.NewBuffer(payload))
-
-// 	if err != nil {
-// 		return "", fmt.Errorf("failed to create new chemical recipe: %w", err)
-// 	}
-
-// 	defer resp.Body.Close()
-
-// 	if resp.StatusCode == http.StatusCreated {
-// 		var result PortalChemicalRecipe
-// 		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-// 			return "", fmt.Errorf("failed to decode response: %w", err)
-// 		}
-// 		return result.ID, nil
-// 	}
-
-// 	bodyBytes, _ := io.ReadAll(resp.Body)
-// 	return "", fmt.Errorf("failed to create new chemical recipe, status code: %d, response: %s", resp.StatusCode, string(bodyBytes))
-// }
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/miru-smart-technologies/Scripts/go/pkg/portal"
+)
+
+// Owners holds the owner every created instance gets, resolved against the Portal before the run starts
+type Owners struct {
+	DefaultID string            // default owner, empty if none is configured
+	byName    map[string]string // lowercased owner column value -> user ID
+}
+
+// normaliseOwnerName lowercases an owner cell and collapses its whitespace
+func normaliseOwnerName(s string) string {
+	return strings.Join(strings.Fields(strings.ToLower(s)), " ")
+}
+
+// ResolveOwners validates the default owner and every distinct value of the owner column in csvFilename.
+// All unknown owners are reported together so the sheet can be fixed in one go.
+func ResolveOwners(client portal.PortalClient, csvFilename string, cols *Columns, defaultOwnerID string) (*Owners, error) {
+	owners := &Owners{byName: map[string]string{}}
+
+	if defaultOwnerID != "" {
+		if _, err := uuid.Parse(defaultOwnerID); err != nil {
+			return nil, fmt.Errorf("default owner %q is not a valid UUID", defaultOwnerID)
+		}
+		res, err := client.CheckIfUserExists(defaultOwnerID)
+		if err != nil {
+			return nil, err
+		}
+		if !res {
+			return nil, fmt.Errorf("default owner %s not found in the Portal", defaultOwnerID)
+		}
+		owners.DefaultID = defaultOwnerID
+	}
+
+	if !cols.HasColumn(cols.Owner) {
+		return owners, nil
+	}
+
+	rows, err := readCsvRows(csvFilename)
+	if err != nil {
+		return nil, err
+	}
+
+	var unknown []string
+	for _, r := range rows {
+		value := cols.GetOptionalValueFromRow(r.row, cols.Owner, "")
+		name := normaliseOwnerName(value)
+		if name == "" {
+			continue
+		}
+		if _, seen := owners.byName[name]; seen {
+			continue
+		}
+
+		res, userID, err := client.CheckIfUserWithNameExists(removeExtraSpace(value))
+		if err != nil {
+			return nil, err
+		}
+		if !res {
+			unknown = append(unknown, removeExtraSpace(value))
+		}
+		owners.byName[name] = userID
+	}
+
+	if len(unknown) > 0 {
+		return nil, fmt.Errorf("owners not found in the Portal: %s", strings.Join(unknown, ", "))
+	}
+
+	return owners, nil
+}
+
+// OwnerFor returns the owner ID for an owner cell, falling back to the default owner when the cell is empty
+func (o *Owners) OwnerFor(value string) string {
+	if id, ok := o.byName[normaliseOwnerName(value)]; ok && id != "" {
+		return id
+	}
+	return o.DefaultID
+}
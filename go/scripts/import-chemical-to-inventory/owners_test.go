@@ -0,0 +1,95 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeOwnersCsv writes a CSV with the owner in its first column and returns its path and columns
+func writeOwnersCsv(t *testing.T, content string) (string, *Columns) {
+	t.Helper()
+
+	csvFile := filepath.Join(t.TempDir(), "chemicals.csv")
+	if err := os.WriteFile(csvFile, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cols := NewColumns()
+	cols.Owner = 0
+	return csvFile, cols
+}
+
+func TestResolveOwnersOfEmptyCsv(t *testing.T) {
+	csvFile, cols := writeOwnersCsv(t, "")
+
+	if _, err := ResolveOwners(newFakePortal(t).client(), csvFile, cols, ""); err == nil {
+		t.Errorf("ResolveOwners of an empty CSV: want an error")
+	}
+}
+
+func TestResolveOwnersSkipsUnreadableRows(t *testing.T) {
+	fake := newFakePortal(t)
+	aliceID := fake.addUser("Alice Smith")
+	bobID := fake.addUser("Bob Jones")
+	csvFile, cols := writeOwnersCsv(t, "Owner,CIID\nAlice Smith,1\nshort row\nBob Jones,3\n")
+
+	owners, err := ResolveOwners(fake.client(), csvFile, cols, "")
+	if err != nil {
+		t.Fatalf("ResolveOwners: %v, want the short row skipped", err)
+	}
+	if owners.OwnerFor("Alice Smith") != aliceID || owners.OwnerFor("Bob Jones") != bobID {
+		t.Errorf("want the owners of the rows around the short one resolved")
+	}
+}
+
+func TestResolveOwnersValidatesDefaultOwner(t *testing.T) {
+	fake := newFakePortal(t)
+	ownerID := fake.addUser("Alice Smith")
+	csvFile, cols := writeOwnersCsv(t, "Owner,CIID\n,1\n")
+
+	owners, err := ResolveOwners(fake.client(), csvFile, cols, ownerID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if owners.DefaultID != ownerID {
+		t.Errorf("DefaultID = %q, want %q", owners.DefaultID, ownerID)
+	}
+
+	for _, defaultOwner := range []string{"Alice Smith", "not-a-uuid", "8d3c0d6e-3b4c-4a56-9e1f-2f7c5a9b1e42"} {
+		if _, err := ResolveOwners(fake.client(), csvFile, cols, defaultOwner); err == nil {
+			t.Errorf("default owner %q: want an error for an invalid or unknown UUID", defaultOwner)
+		}
+	}
+}
+
+func TestResolveOwnersPerRow(t *testing.T) {
+	fake := newFakePortal(t)
+	defaultID := fake.addUser("Lab Manager")
+	aliceID := fake.addUser("Alice Smith")
+	bobID := fake.addUser("Bob Jones")
+	csvFile, cols := writeOwnersCsv(t, "Owner,CIID\nAlice Smith,1\n  alice   SMITH ,2\nBob Jones,3\n,4\n")
+
+	owners, err := ResolveOwners(fake.client(), csvFile, cols, defaultID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := map[string]string{
+		"Alice Smith":     aliceID,
+		" alice   smith ": aliceID,
+		"Bob Jones":       bobID,
+		"":                defaultID,
+	}
+	for value, want := range tests {
+		if got := owners.OwnerFor(value); got != want {
+			t.Errorf("OwnerFor(%q) = %q, want %q", value, got, want)
+		}
+	}
+
+	// all unknown owners are reported at once
+	csvFile, cols = writeOwnersCsv(t, "Owner,CIID\nAlice Smith,1\nCarol White,2\nDan Brown,3\n")
+	_, err = ResolveOwners(fake.client(), csvFile, cols, "")
+	if err == nil || !strings.Contains(err.Error(), "Carol White") || !strings.Contains(err.Error(), "Dan Brown") {
+		t.Errorf("ResolveOwners with unknown owners: %v, want both reported", err)
+	}
+}
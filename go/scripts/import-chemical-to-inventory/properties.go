@@ -0,0 +1,158 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// PhysicalProperties are the physical properties of a chemical; nil means unknown and is sent to the Portal as null
+type PhysicalProperties struct {
+	StateOfMatter   *string
+	Colour          *string
+	MolecularWeight *float64 // g/mol
+	Density         *float64 // g/cm3
+}
+
+// noDataValues are the cells the sheet uses for an unknown property
+var noDataValues = map[string]bool{
+	"":             true,
+	"no data":      true,
+	"n/a":          true,
+	"na":           true,
+	"-":            true,
+	"mixture":      true,
+	"do not enter": true,
+}
+
+// stateWords maps the first word of a state cell to its state of matter, including the sheet's typos and forms
+var stateWords = map[string]string{
+	"solid":       "solid",
+	"soild":       "solid",
+	"powder":      "solid",
+	"crystalline": "solid",
+	"crystal":     "solid",
+	"crystals":    "solid",
+	"white":       "solid", // "White (powder)"
+	"liquid":      "liquid",
+	"gas":         "gas",
+}
+
+var (
+	// parenthesised matches the remarks of a cell, e.g. "(granules)" in "solid (granules)"
+	parenthesised = regexp.MustCompile(`\([^)]*\)`)
+	// leadingNumber matches a number with an optional remark, e.g. "317.26 (anhy)"
+	leadingNumber = regexp.MustCompile(`^~?\s*(\d+(?:\.\d+)?)\s*(\([^)]*\))?$`)
+	// numberRange matches a range such as "1.055-1.095" or "0.7 to 1"
+	numberRange = regexp.MustCompile(`^(\d+(?:\.\d+)?)\s*(?:-|to)\s*(\d+(?:\.\d+)?)$`)
+	// thousandsSeparator matches the comma of "85,000"
+	thousandsSeparator = regexp.MustCompile(`^\d{1,3}(,\d{3})+(\.\d+)?$`)
+)
+
+// isNoData reports whether a cell means the property is unknown
+func isNoData(value string) bool {
+	return noDataValues[strings.ToLower(strings.Join(strings.Fields(value), " "))]
+}
+
+// normaliseStateOfMatter reduces a state cell to solid, liquid or gas, e.g. "solid (granules)" -> solid.
+// A cell listing different states ("liquid, solid (powder)" for a kit) is rejected.
+func normaliseStateOfMatter(raw string) (*string, error) {
+	if isNoData(raw) {
+		return nil, nil
+	}
+
+	text := strings.ToLower(parenthesised.ReplaceAllString(raw, ""))
+	state := ""
+	for _, part := range strings.Split(text, ",") {
+		words := strings.Fields(part)
+		if len(words) == 0 {
+			continue
+		}
+		partState, ok := stateWords[words[0]]
+		if !ok {
+			return nil, fmt.Errorf("unknown state of matter %q", removeExtraSpace(raw))
+		}
+		if state != "" && state != partState {
+			return nil, fmt.Errorf("state of matter %q lists several states", removeExtraSpace(raw))
+		}
+		state = partState
+	}
+
+	if state == "" {
+		return nil, nil
+	}
+	return &state, nil
+}
+
+// normaliseColour trims and lowercases a colour cell
+func normaliseColour(raw string) *string {
+	if isNoData(raw) {
+		return nil
+	}
+	colour := strings.ToLower(strings.Join(strings.Fields(raw), " "))
+	return &colour
+}
+
+// parseMolecularWeight reads a molecular weight like "120.18", "85,000" or "317.26 (anhy)"
+func parseMolecularWeight(raw string) (*float64, error) {
+	if isNoData(raw) {
+		return nil, nil
+	}
+
+	text := strings.Join(strings.Fields(raw), " ")
+	if thousandsSeparator.MatchString(text) {
+		text = strings.ReplaceAll(text, ",", "")
+	}
+	match := leadingNumber.FindStringSubmatch(text)
+	if match == nil {
+		return nil, fmt.Errorf("invalid molecular weight %q", removeExtraSpace(raw))
+	}
+
+	weight, _ := strconv.ParseFloat(match[1], 64)
+	return &weight, nil
+}
+
+// parseDensity reads a density like "0.785" or "~2"; for a range such as "0.8-0.9" the middle is used
+func parseDensity(raw string) (*float64, error) {
+	if isNoData(raw) {
+		return nil, nil
+	}
+
+	text := strings.Join(strings.Fields(raw), " ")
+	if match := numberRange.FindStringSubmatch(text); match != nil {
+		low, _ := strconv.ParseFloat(match[1], 64)
+		high, _ := strconv.ParseFloat(match[2], 64)
+		density := (low + high) / 2
+		return &density, nil
+	}
+
+	match := leadingNumber.FindStringSubmatch(text)
+	if match == nil {
+		return nil, fmt.Errorf("invalid density %q", removeExtraSpace(raw))
+	}
+
+	density, _ := strconv.ParseFloat(match[1], 64)
+	return &density, nil
+}
+
+// buildPhysicalProperties reads the physical property columns of a row.
+// Cells that cannot be read are returned as problems and the property is left unknown.
+func buildPhysicalProperties(row []string, cols *Columns) (PhysicalProperties, []string) {
+	var properties PhysicalProperties
+	var problems []string
+	var err error
+
+	if properties.StateOfMatter, err = normaliseStateOfMatter(cols.GetOptionalValueFromRow(row, cols.StateOfMatter, "")); err != nil {
+		problems = append(problems, err.Error())
+	}
+	properties.Colour = normaliseColour(cols.GetOptionalValueFromRow(row, cols.Colour, ""))
+	if properties.MolecularWeight, err = parseMolecularWeight(cols.GetOptionalValueFromRow(row, cols.MolecularWeight, "")); err != nil {
+		problems = append(problems, err.Error())
+	}
+	if properties.Density, err = parseDensity(cols.GetOptionalValueFromRow(row, cols.Density, "")); err != nil {
+		problems = append(problems, err.Error())
+	}
+
+	return properties, problems
+}
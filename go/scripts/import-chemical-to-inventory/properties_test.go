@@ -0,0 +1,70 @@
+package main
+
+import "testing"
+
+func TestNormaliseStateOfMatter(t *testing.T) {
+	tests := map[string]string{ // raw -> state, "" for unknown
+		"liquid ":                 "liquid",
+		" solid":                  "solid",
+		"solid (granules)":        "solid",
+		"Solid (Crystalline)":     "solid",
+		"soild":                   "solid",
+		"powder":                  "solid",
+		"solid to liquid (melt)":  "solid",
+		"liquid (viscous liquid)": "liquid",
+		"gas":                     "gas",
+		"no data":                 "",
+		" ":                       "",
+		"solid (powder), solid":   "solid",
+	}
+	for raw, want := range tests {
+		got, err := normaliseStateOfMatter(raw)
+		if err != nil {
+			t.Errorf("%q: %v", raw, err)
+			continue
+		}
+		if (got == nil && want != "") || (got != nil && *got != want) {
+			t.Errorf("%q = %v, want %q", raw, got, want)
+		}
+	}
+
+	for _, raw := range []string{"liquid, solid (powder)", "plasma"} {
+		if got, err := normaliseStateOfMatter(raw); err == nil {
+			t.Errorf("%q = %v, want an error", raw, *got)
+		}
+	}
+}
+
+func TestParseMolecularWeightAndDensity(t *testing.T) {
+	weights := map[string]float64{"120.18": 120.18, " 46.07 ": 46.07, "85,000": 85000, "317.26 (anhy)": 317.26, "668": 668}
+	for raw, want := range weights {
+		got, err := parseMolecularWeight(raw)
+		if err != nil || got == nil || *got != want {
+			t.Errorf("parseMolecularWeight(%q) = %v, %v; want %v", raw, got, err, want)
+		}
+	}
+	for _, raw := range []string{"", "mixture", "no data"} {
+		if got, err := parseMolecularWeight(raw); got != nil || err != nil {
+			t.Errorf("parseMolecularWeight(%q) = %v, %v; want unknown", raw, got, err)
+		}
+	}
+	for _, raw := range []string{"0.88kg", "average Mn ~10,000"} {
+		if _, err := parseMolecularWeight(raw); err == nil {
+			t.Errorf("parseMolecularWeight(%q): want an error", raw)
+		}
+	}
+
+	densities := map[string]float64{"0.785": 0.785, "1.10": 1.1, "~2": 2, "0.8-0.9": 0.85, "0.7 to 1": 0.85}
+	for raw, want := range densities {
+		got, err := parseDensity(raw)
+		if err != nil || got == nil || *got-want > 1e-9 || want-*got > 1e-9 {
+			t.Errorf("parseDensity(%q) = %v, %v; want %v", raw, got, err, want)
+		}
+	}
+	if got, err := parseDensity("no data"); got != nil || err != nil {
+		t.Errorf("parseDensity(no data) = %v, %v; want unknown", got, err)
+	}
+	if _, err := parseDensity(">1"); err == nil {
+		t.Errorf("parseDensity(>1): want an error")
+	}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	UnitKilogram = "kg"
+	UnitLitre    = "L"
+)
+
+// Quantity is an amount in a canonical unit: kg for masses, and for volumes whose density is known; L for other volumes
+type Quantity struct {
+	Value float64
+	Unit  string // UnitKilogram or UnitLitre, empty if the amount is unknown
+}
+
+// massUnits and volumeUnits hold the factor from a unit of the sheet to kg and L
+var massUnits = map[string]float64{
+	"mg":  1e-6,
+	"g":   1e-3,
+	"kg":  1,
+	"kgs": 1,
+	"lb":  0.45359237,
+	"lbs": 0.45359237,
+	"oz":  0.028349523125,
+}
+
+var volumeUnits = map[string]float64{
+	"µl":     1e-6,
+	"ul":     1e-6,
+	"ml":     1e-3,
+	"l":      1,
+	"fl oz":  0.0295735295625,
+	"pint":   0.473176473,
+	"pt":     0.473176473,
+	"quart":  0.946352946,
+	"qt":     0.946352946,
+	"gal":    3.785411784,
+	"gallon": 3.785411784,
+}
+
+var (
+	// singleQuantity matches "250 g", "2L", "~1.5 L" or "8 fl oz"
+	singleQuantity = regexp.MustCompile(`^~?\s*(\d+(?:\.\d+)?)\s*([a-zµ]+(?: oz)?)$`)
+	// countFirst and countLast match the number of containers of "2 x 250 g" and "1.13 kg x 6"
+	countFirst = regexp.MustCompile(`^(\d+)\s*x\s*(.+)$`)
+	countLast  = regexp.MustCompile(`^(.+?)\s*x\s*(\d+)$`)
+	// trailingRemark matches a remark after the quantity, e.g. " (473 mL)" in "1 pint (473 mL)"
+	trailingRemark = regexp.MustCompile(`\s*\([^)]*\)$`)
+)
+
+// parseQuantity reads one quantity like "250 g" or "1 pint" into kg or L
+func parseQuantity(raw string) (value float64, unit string, err error) {
+	text := strings.ToLower(strings.Join(strings.Fields(raw), " "))
+	text = strings.TrimSpace(strings.TrimSuffix(text, "?"))
+	text = trailingRemark.ReplaceAllString(text, "")
+
+	match := singleQuantity.FindStringSubmatch(text)
+	if match == nil {
+		return 0, "", fmt.Errorf("cannot read amount %q", removeExtraSpace(raw))
+	}
+
+	number, _ := strconv.ParseFloat(match[1], 64)
+	if factor, ok := massUnits[match[2]]; ok {
+		return number * factor, UnitKilogram, nil
+	}
+	if factor, ok := volumeUnits[match[2]]; ok {
+		return number * factor, UnitLitre, nil
+	}
+	return 0, "", fmt.Errorf("unknown unit %q in amount %q", match[2], removeExtraSpace(raw))
+}
+
+// ParseAmount reads an amount cell into the number of containers and the quantity of each,
+// e.g. "2 x 250 g" is 2 containers of 0.25 kg. Volumes are converted to kg when density (g/cm3) is known.
+func ParseAmount(raw string, density *float64) (int, Quantity, error) {
+	text := strings.ToLower(strings.Join(strings.Fields(raw), " "))
+
+	count := 1
+	if match := countFirst.FindStringSubmatch(text); match != nil {
+		count, _ = strconv.Atoi(match[1])
+		text = match[2]
+	} else if match := countLast.FindStringSubmatch(text); match != nil {
+		count, _ = strconv.Atoi(match[2])
+		text = match[1]
+	}
+	if count < 1 {
+		return 0, Quantity{}, fmt.Errorf("invalid number of containers in amount %q", removeExtraSpace(raw))
+	}
+
+	value, unit, err := parseQuantity(text)
+	if err != nil {
+		return 0, Quantity{}, fmt.Errorf("cannot read amount %q", removeExtraSpace(raw))
+	}
+
+	// g/cm3 is kg/L
+	if unit == UnitLitre && density != nil {
+		return count, Quantity{Value: value * *density, Unit: UnitKilogram}, nil
+	}
+	return count, Quantity{Value: value, Unit: unit}, nil
+}
+
+// containerAmounts returns the quantity of every container of a row.
+// The amount column is read first; the "amount, kg" column of newer exports is used when the amount
+// can't be read or is a volume of unknown density. If neither works the row is one container of unknown
+// amount and the problem is returned.
+func containerAmounts(row []string, cols *Columns, density *float64) ([]Quantity, error) {
+	raw := removeExtraSpace(cols.GetOptionalValueFromRow(row, cols.Amount, ""))
+
+	count := 1
+	var quantity Quantity
+	var err error
+	if raw != "" {
+		count, quantity, err = ParseAmount(raw, density)
+		if err != nil {
+			count = 1
+		}
+	}
+
+	if quantity.Unit != UnitKilogram {
+		if total, ok := amountInKg(row, cols); ok {
+			quantity = Quantity{Value: total / float64(count), Unit: UnitKilogram}
+			if err != nil {
+				err = fmt.Errorf("%w - using the amount in kg column instead", err)
+			}
+		}
+	}
+
+	containers := make([]Quantity, count)
+	for i := range containers {
+		containers[i] = quantity
+	}
+	return containers, err
+}
+
+// amountInKg reads the total amount of a row from the "amount, kg" and unit columns
+func amountInKg(row []string, cols *Columns) (float64, bool) {
+	value := removeExtraSpace(cols.GetOptionalValueFromRow(row, cols.AmountKg, ""))
+	if value == "" {
+		return 0, false
+	}
+
+	unit := removeExtraSpace(cols.GetOptionalValueFromRow(row, cols.AmountUnit, UnitKilogram))
+	total, unit, err := parseQuantity(value + " " + unit)
+	if err != nil || unit != UnitKilogram {
+		return 0, false
+	}
+	return total, true
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParseAmount(t *testing.T) {
+	density := 0.8
+	tests := []struct {
+		raw      string
+		density  *float64
+		count    int
+		quantity Quantity
+	}{
+		{"4 L", nil, 1, Quantity{4, UnitLitre}},
+		{"1 kg", nil, 1, Quantity{1, UnitKilogram}},
+		{"500g", nil, 1, Quantity{0.5, UnitKilogram}},
+		{"2 x 250 g", nil, 2, Quantity{0.25, UnitKilogram}},
+		{"1.13 kg x 6", nil, 6, Quantity{1.13, UnitKilogram}},
+		{"3x~100 g", nil, 3, Quantity{0.1, UnitKilogram}},
+		{"1 pint (473 mL)", nil, 1, Quantity{0.473176473, UnitLitre}},
+		{"500 g ?", nil, 1, Quantity{0.5, UnitKilogram}},
+		{"100 mL", &density, 1, Quantity{0.08, UnitKilogram}},
+	}
+	for _, tt := range tests {
+		count, quantity, err := ParseAmount(tt.raw, tt.density)
+		if err != nil {
+			t.Errorf("ParseAmount(%q): %v", tt.raw, err)
+			continue
+		}
+		if count != tt.count || quantity.Unit != tt.quantity.Unit || math.Abs(quantity.Value-tt.quantity.Value) > 1e-9 {
+			t.Errorf("ParseAmount(%q) = %d x %v, want %d x %v", tt.raw, count, quantity, tt.count, tt.quantity)
+		}
+	}
+
+	for _, raw := range []string{"vial", "2 sheets (4\"x4\")", "5 cylinders", "0 x 1 kg"} {
+		if _, _, err := ParseAmount(raw, nil); err == nil {
+			t.Errorf("ParseAmount(%q): want an error", raw)
+		}
+	}
+}
+
+func TestContainerAmountsFallsBackToKgColumn(t *testing.T) {
+	cols := NewColumns()
+	cols.Amount, cols.AmountKg, cols.AmountUnit = 0, 1, 2
+
+	tests := []struct {
+		row        []string
+		containers []Quantity
+		wantErr    bool
+	}{
+		{[]string{"2 x 250 g", "0.5", "kg"}, []Quantity{{0.25, UnitKilogram}, {0.25, UnitKilogram}}, false},
+		{[]string{"2 x 1 L", "1.6", "kg"}, []Quantity{{0.8, UnitKilogram}, {0.8, UnitKilogram}}, false},
+		{[]string{"500 mL jar", "0.45", "kg"}, []Quantity{{0.45, UnitKilogram}}, true},
+		{[]string{"", "2", "kg"}, []Quantity{{2, UnitKilogram}}, false},
+		{[]string{"1 box", "1", "box"}, []Quantity{{}}, true},
+		{[]string{"", "", ""}, []Quantity{{}}, false},
+	}
+	for _, tt := range tests {
+		containers, err := containerAmounts(tt.row, cols, nil)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("containerAmounts(%q) error = %v, want error %v", tt.row, err, tt.wantErr)
+		}
+		if len(containers) != len(tt.containers) {
+			t.Errorf("containerAmounts(%q) = %v, want %v", tt.row, containers, tt.containers)
+			continue
+		}
+		for i := range containers {
+			if containers[i].Unit != tt.containers[i].Unit || math.Abs(containers[i].Value-tt.containers[i].Value) > 1e-9 {
+				t.Errorf("containerAmounts(%q) = %v, want %v", tt.row, containers, tt.containers)
+				break
+			}
+		}
+	}
+}
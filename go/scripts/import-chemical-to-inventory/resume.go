@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// ResumeLog holds the successful steps of a previous processed log. When resuming a run, the steps it recorded
+// as successful are not repeated; their database IDs are reused for the downstream steps instead.
+type ResumeLog struct {
+	ids    map[int]map[string]string // row -> record kind -> database ID
+	reused atomic.Int64              // number of steps reused so far in this run
+}
+
+// kindFromStep extracts the record kind from a processed log step name,
+// e.g. "Check if chemical recipe already exists" and "Create new chemical recipe" both give "chemical recipe"
+func kindFromStep(step string) (string, bool) {
+	if strings.HasPrefix(step, "Check if ") && strings.HasSuffix(step, " already exists") {
+		return strings.TrimSuffix(strings.TrimPrefix(step, "Check if "), " already exists"), true
+	}
+	if strings.HasPrefix(step, "Create new ") {
+		return strings.TrimPrefix(step, "Create new "), true
+	}
+	return "", false
+}
+
+// LoadResumeLog reads a processed log written by a previous run (log-*.csv).
+// Rows resumed in that run count as successful too, so a run can be resumed more than once.
+func LoadResumeLog(filename string) (*ResumeLog, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open processed log: %w", err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read processed log: %w", err)
+	}
+
+	if len(records) == 0 || len(records[0]) < 4 || records[0][0] != "FileRowNum" {
+		return nil, fmt.Errorf("%s is not a processed log", filename)
+	}
+
+	r := &ResumeLog{ids: map[int]map[string]string{}}
+	for i, record := range records[1:] {
+		rowNum, err := strconv.Atoi(record[0])
+		if err != nil {
+			return nil, fmt.Errorf("line %d of processed log: invalid FileRowNum %q", i+2, record[0])
+		}
+
+		step, status, databaseID := record[1], record[2], record[3]
+		if (status != "success" && status != "resumed") || databaseID == "" {
+			continue
+		}
+
+		kind, ok := kindFromStep(step)
+		if !ok {
+			continue
+		}
+
+		if r.ids[rowNum] == nil {
+			r.ids[rowNum] = map[string]string{}
+		}
+		r.ids[rowNum][kind] = databaseID
+	}
+
+	return r, nil
+}
+
+// Succeeded returns the database ID of a step that already succeeded for the row. Safe to call on a nil ResumeLog.
+func (r *ResumeLog) Succeeded(rowNum int, kind string) (string, bool) {
+	if r == nil {
+		return "", false
+	}
+	id, ok := r.ids[rowNum][kind]
+	return id, ok
+}
+
+// Reused returns the number of steps reused so far in this run
+func (r *ResumeLog) Reused() int {
+	return int(r.reused.Load())
+}
+
+// RowCount returns the number of rows with at least one successful step
+func (r *ResumeLog) RowCount() int {
+	return len(r.ids)
+}
+
+// logResumed records that a step was taken over from resumeLog, the log being resumed
+func logResumed(resumeLog *ResumeLog, writer *LogWriter, rowNum int, kind string, name string, id string) {
+	fmt.Printf("%s %s already processed in the resumed run - reusing\n", capitalise(kind), name)
+	writeProcessedLog(writer.withoutCall(), rowNum, "Check if "+kind+" already exists", "resumed", id, "")
+	resumeLog.reused.Add(1)
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ReviewOptions holds the command-line flags of the review-duplicates command
+type ReviewOptions struct {
+	MappingFile     string
+	CsvFile         string // overrides RAW_CSV in the mapping file
+	LayoutsDir      string
+	ChemicalAliases string
+	OutputFile      string // duplicate report path
+}
+
+func ParseReviewFlags(args []string) (*ReviewOptions, error) {
+	opts := &ReviewOptions{}
+
+	fs := flag.NewFlagSet("review-duplicates", flag.ContinueOnError)
+	fs.StringVar(&opts.MappingFile, "mapping", "chemical_inventory.env", "mapping env file")
+	fs.StringVar(&opts.CsvFile, "csv", "", "input CSV to review (default: RAW_CSV from the mapping file)")
+	fs.StringVar(&opts.LayoutsDir, "layouts", "layouts", "directory of the known sheet layouts (*.env)")
+	fs.StringVar(&opts.ChemicalAliases, "chemicals", "chemical_aliases.csv", "chemical alias CSV: reviewed names to merge into one chemical")
+	fs.StringVar(&opts.OutputFile, "out", "", "duplicate report output path (default: duplicates-YYYY-MM-DD-HH-MM.csv)")
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if opts.OutputFile == "" {
+		opts.OutputFile = "duplicates-" + time.Now().Format("2006-01-02-15-04") + ".csv"
+	}
+
+	return opts, nil
+}
+
+// RunReviewDuplicates writes the chemical names of the CSV that are, or may be, the same chemical to a report.
+// Nothing is read from or written to the Portal, so it can be run before the import.
+func RunReviewDuplicates(opts *ReviewOptions) error {
+	cols := NewColumns()
+	if err := cols.LoadFromEnv(opts.MappingFile); err != nil {
+		return err
+	}
+	if opts.CsvFile != "" {
+		cols.RawCsv = opts.CsvFile
+	}
+	if cols.RawCsv == "" {
+		return fmt.Errorf("no input CSV - set RAW_CSV in the mapping file or use -csv")
+	}
+
+	if _, err := ApplySheetLayout(cols, opts.LayoutsDir); err != nil {
+		return err
+	}
+	aliases, err := LoadChemicalAliases(opts.ChemicalAliases)
+	if err != nil {
+		return err
+	}
+	names, err := ResolveChemicalNames(cols.RawCsv, cols, aliases)
+	if err != nil {
+		return err
+	}
+
+	for _, group := range names.Groups() {
+		if group.NeedsReview() {
+			fmt.Printf("REVIEW  %s\n", strings.Join(sortedGroupNames(group), " | "))
+		} else {
+			fmt.Printf("merged  %s (%d spellings)\n", group.Variants[0].Resolved, len(group.Variants))
+		}
+	}
+
+	if err := WriteDuplicateReport(opts.OutputFile, names.Groups()); err != nil {
+		return err
+	}
+
+	fmt.Printf("%d group(s) of names merged, %d to review - report written to %s\n",
+		len(names.Groups())-names.ReviewCount(), names.ReviewCount(), opts.OutputFile)
+	if names.ReviewCount() > 0 {
+		fmt.Printf("To merge a group, add its names to %s and run the review again\n", opts.ChemicalAliases)
+	}
+	return nil
+}
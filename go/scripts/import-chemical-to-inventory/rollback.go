@@ -0,0 +1,286 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/miru-smart-technologies/Scripts/go/pkg/portal"
+)
+
+// rollbackOrder lists the record kinds a run creates, dependants first, with the client method that deletes them
+// and the kinds of the records of their row they point at: instances point at recipes, suppliers and locations,
+// and recipes point at chemicals.
+var rollbackOrder = []struct {
+	kind    string
+	delete  func(client portal.PortalClient, id string) error
+	parents []string
+}{
+	{"chemical instance", portal.PortalClient.DeleteChemicalInstance, []string{"chemical recipe", "supplier", "location"}},
+	{"chemical recipe", portal.PortalClient.DeleteChemicalRecipe, []string{"chemical"}},
+	{"chemical", portal.PortalClient.DeleteChemical, nil},
+	{"supplier", portal.PortalClient.DeleteSupplier, nil},
+	{"location", portal.PortalClient.DeleteLocation, nil},
+}
+
+// RollbackOptions holds the command-line flags of the rollback command
+type RollbackOptions struct {
+	LogFile     string // processed log of the run to roll back
+	MappingFile string
+	ApiBaseUrl  string
+	Stage       string
+	OutputFile  string // rollback log path
+	DryRun      bool   // only preview what would be deleted
+	Yes         bool   // skip the confirmation prompt
+}
+
+// RollbackRecord is a record created by the run being rolled back
+type RollbackRecord struct {
+	FileRowNum int
+	Kind       string
+	DatabaseID string
+	Parents    []string // IDs of the records of its row it points at, found or created
+}
+
+func ParseRollbackFlags(args []string) (*RollbackOptions, error) {
+	opts := &RollbackOptions{}
+
+	fs := flag.NewFlagSet("rollback", flag.ContinueOnError)
+	fs.StringVar(&opts.LogFile, "log", "", "processed log (log-*.csv) of the run to roll back")
+	fs.StringVar(&opts.MappingFile, "mapping", "chemical_inventory.env", "mapping env file with the API base URLs")
+	fs.StringVar(&opts.ApiBaseUrl, "api", "", "Portal API base URL (default: API_BASE_URL_TEST or API_BASE_URL_PRODUCTION from the mapping file)")
+	fs.StringVar(&opts.Stage, "stage", StageTest, "target stage: test or production")
+	fs.StringVar(&opts.OutputFile, "out", "", "rollback log output path (default: rollback-YYYY-MM-DD-HH-MM.csv)")
+	fs.BoolVar(&opts.DryRun, "dry-run", false, "only list what would be deleted")
+	fs.BoolVar(&opts.Yes, "yes", false, "delete without asking for confirmation")
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if opts.LogFile == "" {
+		return nil, fmt.Errorf("-log is required")
+	}
+	if opts.Stage != StageTest && opts.Stage != StageProduction {
+		return nil, fmt.Errorf("unknown stage %q - expected %s or %s", opts.Stage, StageTest, StageProduction)
+	}
+	if opts.OutputFile == "" {
+		opts.OutputFile = "rollback-" + time.Now().Format("2006-01-02-15-04") + ".csv"
+	}
+
+	return opts, nil
+}
+
+// LoadRollbackRecords reads the records a run created from its processed log, in deletion order
+func LoadRollbackRecords(filename string) ([]RollbackRecord, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open processed log: %w", err)
+	}
+	defer file.Close()
+
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read processed log: %w", err)
+	}
+	if len(records) == 0 || len(records[0]) < 4 || records[0][0] != "FileRowNum" {
+		return nil, fmt.Errorf("%s is not a processed log", filename)
+	}
+
+	created := map[string][]RollbackRecord{}
+	seen := map[string]bool{}
+	// the records each row created or found, by kind, which the records it created point at
+	rowRecords := map[int]map[string][]string{}
+	for i, record := range records[1:] {
+		kind, isCreate, ok := logRecordKind(record[1])
+		if !ok || record[3] == "" || (record[2] != "success" && record[2] != "resumed") {
+			continue
+		}
+
+		rowNum, err := strconv.Atoi(record[0])
+		if err != nil {
+			return nil, fmt.Errorf("line %d of processed log: invalid FileRowNum %q", i+2, record[0])
+		}
+		if rowRecords[rowNum] == nil {
+			rowRecords[rowNum] = map[string][]string{}
+		}
+		rowRecords[rowNum][kind] = append(rowRecords[rowNum][kind], record[3])
+
+		// only records this run created - "resumed" rows were created by the run that was resumed
+		if !isCreate || record[2] != "success" || seen[record[3]] {
+			continue
+		}
+		seen[record[3]] = true
+		created[kind] = append(created[kind], RollbackRecord{FileRowNum: rowNum, Kind: kind, DatabaseID: record[3]})
+	}
+
+	var ordered []RollbackRecord
+	for _, step := range rollbackOrder {
+		// newest first, so an instance is deleted before the parent instance it was split from
+		for i := len(created[step.kind]) - 1; i >= 0; i-- {
+			record := created[step.kind][i]
+			for _, parent := range step.parents {
+				record.Parents = append(record.Parents, rowRecords[record.FileRowNum][parent]...)
+			}
+			ordered = append(ordered, record)
+		}
+		delete(created, step.kind)
+	}
+	for kind := range created {
+		return nil, fmt.Errorf("don't know how to roll back %q records", kind)
+	}
+
+	return ordered, nil
+}
+
+// logRecordKind returns the kind of record a step of the processed log found or created, without the container
+func logRecordKind(step string) (kind string, isCreate bool, ok bool) {
+	if kind, ok := strings.CutPrefix(step, "Create new "); ok {
+		return recordKind(kind), true, true
+	}
+	if kind, ok := strings.CutPrefix(step, "Check if "); ok {
+		if kind, ok := strings.CutSuffix(kind, " already exists"); ok {
+			return recordKind(kind), false, true
+		}
+	}
+	return "", false, false
+}
+
+// deleteRecordFromDB deletes a record; one the Portal answers 404 for is gone already, which is what the
+// rollback is after
+func deleteRecordFromDB(client portal.PortalClient, kind string, id string) error {
+	for _, step := range rollbackOrder {
+		if step.kind == kind {
+			err := step.delete(client, id)
+			var apiErr *portal.APIError
+			if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+				fmt.Printf("%s %s is not in the DB any more\n", capitalise(kind), id)
+				return nil
+			}
+			return err
+		}
+	}
+	return fmt.Errorf("don't know how to delete %q records", kind)
+}
+
+// ApplyMapping fills the API base URL from the mapping file unless -api was given
+func (opts *RollbackOptions) ApplyMapping() error {
+	env, err := godotenv.Read(opts.MappingFile)
+	if err != nil {
+		return fmt.Errorf("error loading .env file: %w", err)
+	}
+	baseUrl, err := stageApiBaseUrl(env, opts.Stage, opts.ApiBaseUrl)
+	if err != nil {
+		return err
+	}
+	opts.ApiBaseUrl = baseUrl
+	return nil
+}
+
+// RunRollback deletes everything the run behind opts.LogFile created from the Portal behind client
+func RunRollback(opts *RollbackOptions, client portal.PortalClient) error {
+	baseUrl := opts.ApiBaseUrl
+
+	toDelete, err := LoadRollbackRecords(opts.LogFile)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Records created by %s:\n", opts.LogFile)
+	counts := map[string]int{}
+	for _, record := range toDelete {
+		fmt.Printf("\trow %d: %s %s\n", record.FileRowNum, record.Kind, record.DatabaseID)
+		counts[record.Kind]++
+	}
+	for _, step := range rollbackOrder {
+		fmt.Printf("%-20s %d\n", step.kind+"s:", counts[step.kind])
+	}
+
+	if len(toDelete) == 0 {
+		fmt.Println("Nothing to roll back")
+		return nil
+	}
+
+	if opts.DryRun {
+		fmt.Printf("Dry run - %d records would be deleted from %s (%s stage)\n", len(toDelete), baseUrl, opts.Stage)
+		return nil
+	}
+
+	if !opts.Yes {
+		fmt.Printf("Delete these %d records from %s (%s stage)? Type \"yes\" to continue: ", len(toDelete), baseUrl, opts.Stage)
+		answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		if strings.TrimSpace(answer) != "yes" {
+			fmt.Println("Rollback cancelled")
+			return nil
+		}
+	}
+
+	rollbackLog, err := os.Create(opts.OutputFile)
+	if err != nil {
+		return fmt.Errorf("failed to create rollback log file: %w", err)
+	}
+	defer rollbackLog.Close()
+
+	writer := &LogWriter{Writer: csv.NewWriter(rollbackLog)}
+	// the log records the attempts of the deletes when the client retries them
+	writer.retrier, _ = client.(*portal.RetryClient)
+	defer writer.Flush()
+
+	writer.Write([]string{"FileRowNum",
+		"Type",
+		"Status",
+		"DatabaseID",
+		"ErrorMsg",
+		"ProcessedAt",
+		"Attempts"})
+
+	deletedCount := 0
+	errorCount := 0
+	skippedCount := 0
+	// records still pointed at by a record that could not be deleted; deleting them would fail or leave it dangling
+	kept := map[string]bool{}
+	for _, record := range toDelete {
+		if kept[record.DatabaseID] {
+			fmt.Printf("Not deleting %s %s - a record pointing at it could not be deleted\n", record.Kind, record.DatabaseID)
+			writeProcessedLog(writer.withoutCall(), record.FileRowNum, "Delete "+record.Kind, "skipped", record.DatabaseID, "a record pointing at it could not be deleted")
+			markKept(kept, record.Parents)
+			skippedCount++
+			continue
+		}
+		if err := deleteRecordFromDB(client, record.Kind, record.DatabaseID); err != nil {
+			fmt.Printf("Error deleting %s %s: %v\n", record.Kind, record.DatabaseID, err)
+			writeProcessedLog(writer, record.FileRowNum, "Delete "+record.Kind, "cannot delete "+record.Kind, record.DatabaseID, err.Error())
+			markKept(kept, record.Parents)
+			errorCount++
+			continue
+		}
+		fmt.Printf("Deleted %s %s\n", record.Kind, record.DatabaseID)
+		writeProcessedLog(writer, record.FileRowNum, "Delete "+record.Kind, "success", record.DatabaseID, "")
+		deletedCount++
+	}
+
+	fmt.Println("\n=== Rollback Summary ===")
+	fmt.Printf("Rollback log created:          %s\n", rollbackLog.Name())
+	fmt.Printf("Records deleted:               %d\n", deletedCount)
+	fmt.Printf("Delete errors:                 %d\n", errorCount)
+	fmt.Printf("Records kept for those:        %d\n", skippedCount)
+
+	if errorCount > 0 {
+		return fmt.Errorf("%d records could not be deleted and %d records they point at were kept - see %s", errorCount, skippedCount, rollbackLog.Name())
+	}
+	return nil
+}
+
+func markKept(kept map[string]bool, ids []string) {
+	for _, id := range ids {
+		kept[id] = true
+	}
+}
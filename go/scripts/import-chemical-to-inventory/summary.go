@@ -0,0 +1,164 @@
+package main
+
+import (
+	"fmt"
+)
+
+// Summary counts what a run did and is printed as the Processing Summary at the end of it
+type Summary struct {
+	LogFile          string
+	Layout           string // name of the detected sheet layout
+	DefaultOwner     string
+	RowCount         int
+	ResumedStepCount int
+
+	CreatedChemicalCount       int
+	CreatedRecipeCount         int
+	EmptyRecipeCount           int
+	CreatedSupplierCount       int
+	EmptySupplierCount         int
+	CreatedLocationCount       int
+	EmptyLocationCount         int
+	UnresolvedLocationCount    int
+	CreatedInstanceCount       int
+	InstanceWithoutRecipeCount int
+	DuplicateReviewCount       int // groups of chemical names that may be one chemical, see review-duplicates
+	InvalidCasCount            int // CAS numbers with a bad format or check digit, not counted as errors
+	InvalidTransportCount      int // UN numbers, classes and packing groups that can't be read or don't go together, not counted as errors
+	InvalidGhsCategoryCount    int // GHS cells kept as notes, not counted as errors
+	InvalidPropertyCount       int // physical property cells left unknown, not counted as errors
+	UnparseableAmountCount     int // instances created without an amount, or with the amount of the kg column, not counted as errors
+	InvalidDateCount           int // date cells left out of the instance, not counted as errors
+	UnverifiedLookupCount      int // created chemicals a lookup by name doesn't find again, not counted as errors
+
+	ErrorCount                      int
+	ChemicalValidationErrorCount    int
+	CheckChemicalErrorCount         int
+	CreateChemicalErrorCount        int
+	MissingChemicalIDErrorCount     int
+	CheckRecipeErrorCount           int
+	CreateRecipeErrorCount          int
+	CheckSupplierErrorCount         int
+	CreateSupplierErrorCount        int
+	CheckLocationErrorCount         int
+	CreateLocationErrorCount        int
+	InstanceValidationErrorCount    int
+	CheckInstanceErrorCount         int
+	MissingParentInstanceErrorCount int
+	CreateInstanceErrorCount        int
+}
+
+// IsErrorCountConsistent checks that ErrorCount is the sum of the error breakdown
+func (s *Summary) IsErrorCountConsistent() bool {
+	return s.ErrorCount == (s.ChemicalValidationErrorCount +
+		s.CheckChemicalErrorCount +
+		s.CreateChemicalErrorCount +
+		s.MissingChemicalIDErrorCount +
+		s.CheckRecipeErrorCount +
+		s.CreateRecipeErrorCount +
+		s.CheckSupplierErrorCount +
+		s.CreateSupplierErrorCount +
+		s.CheckLocationErrorCount +
+		s.CreateLocationErrorCount +
+		s.InstanceValidationErrorCount +
+		s.CheckInstanceErrorCount +
+		s.MissingParentInstanceErrorCount +
+		s.CreateInstanceErrorCount)
+}
+
+// Add adds the counts of other, e.g. of one row, to s. RowCount, ResumedStepCount and DuplicateReviewCount
+// are set by the run as a whole and are not added.
+func (s *Summary) Add(other *Summary) {
+	s.CreatedChemicalCount += other.CreatedChemicalCount
+	s.CreatedRecipeCount += other.CreatedRecipeCount
+	s.EmptyRecipeCount += other.EmptyRecipeCount
+	s.CreatedSupplierCount += other.CreatedSupplierCount
+	s.EmptySupplierCount += other.EmptySupplierCount
+	s.CreatedLocationCount += other.CreatedLocationCount
+	s.EmptyLocationCount += other.EmptyLocationCount
+	s.UnresolvedLocationCount += other.UnresolvedLocationCount
+	s.CreatedInstanceCount += other.CreatedInstanceCount
+	s.InstanceWithoutRecipeCount += other.InstanceWithoutRecipeCount
+	s.InvalidCasCount += other.InvalidCasCount
+	s.InvalidTransportCount += other.InvalidTransportCount
+	s.InvalidGhsCategoryCount += other.InvalidGhsCategoryCount
+	s.InvalidPropertyCount += other.InvalidPropertyCount
+	s.UnparseableAmountCount += other.UnparseableAmountCount
+	s.InvalidDateCount += other.InvalidDateCount
+	s.UnverifiedLookupCount += other.UnverifiedLookupCount
+
+	s.ErrorCount += other.ErrorCount
+	s.ChemicalValidationErrorCount += other.ChemicalValidationErrorCount
+	s.CheckChemicalErrorCount += other.CheckChemicalErrorCount
+	s.CreateChemicalErrorCount += other.CreateChemicalErrorCount
+	s.MissingChemicalIDErrorCount += other.MissingChemicalIDErrorCount
+	s.CheckRecipeErrorCount += other.CheckRecipeErrorCount
+	s.CreateRecipeErrorCount += other.CreateRecipeErrorCount
+	s.CheckSupplierErrorCount += other.CheckSupplierErrorCount
+	s.CreateSupplierErrorCount += other.CreateSupplierErrorCount
+	s.CheckLocationErrorCount += other.CheckLocationErrorCount
+	s.CreateLocationErrorCount += other.CreateLocationErrorCount
+	s.InstanceValidationErrorCount += other.InstanceValidationErrorCount
+	s.CheckInstanceErrorCount += other.CheckInstanceErrorCount
+	s.MissingParentInstanceErrorCount += other.MissingParentInstanceErrorCount
+	s.CreateInstanceErrorCount += other.CreateInstanceErrorCount
+}
+
+func (s *Summary) Print(opts *Options, cols *Columns) {
+	fmt.Println()
+
+	if opts.DryRun {
+		fmt.Println("\n=== Processing Summary (dry run - nothing was written to the Portal) ===")
+		fmt.Printf("Plan file created:             %s\n", s.LogFile)
+	} else {
+		fmt.Println("\n=== Processing Summary ===")
+		fmt.Printf("Log file created:              %s\n", s.LogFile)
+	}
+	fmt.Printf("Stage:                         %s (%s)\n", opts.Stage, cols.ApiBaseUrl)
+	if opts.ResumeFrom != "" {
+		fmt.Printf("Resumed from:                  %s\n", opts.ResumeFrom)
+		fmt.Printf("Steps reused from that log:    %d\n", s.ResumedStepCount)
+	}
+	fmt.Printf("Sheet layout:                  %s\n", s.Layout)
+	fmt.Printf("Total rows processed:          %d\n", s.RowCount)
+	fmt.Printf("Default owner:                 %s\n", s.DefaultOwner)
+	fmt.Printf("Chemicals created:             %d\n", s.CreatedChemicalCount)
+	fmt.Printf("Chemical recipes created:      %d\n", s.CreatedRecipeCount)
+	fmt.Printf("Empty recipe rows:             %d\n", s.EmptyRecipeCount)
+	fmt.Printf("Suppliers created:             %d\n", s.CreatedSupplierCount)
+	fmt.Printf("Empty supplier rows:           %d\n", s.EmptySupplierCount)
+	fmt.Printf("Locations created:             %d\n", s.CreatedLocationCount)
+	fmt.Printf("Empty location rows:           %d\n", s.EmptyLocationCount)
+	fmt.Printf("Unresolved location rows:      %d\n", s.UnresolvedLocationCount)
+	fmt.Printf("Chemical instances created:    %d\n", s.CreatedInstanceCount)
+	fmt.Printf("Instances without recipe:      %d\n", s.InstanceWithoutRecipeCount)
+	fmt.Printf("Possible duplicate chemicals:  %d\n", s.DuplicateReviewCount)
+	fmt.Printf("Invalid CAS numbers:           %d\n", s.InvalidCasCount)
+	fmt.Printf("Invalid transport classes:     %d\n", s.InvalidTransportCount)
+	fmt.Printf("Invalid GHS categories:        %d\n", s.InvalidGhsCategoryCount)
+	fmt.Printf("Invalid physical properties:   %d\n", s.InvalidPropertyCount)
+	fmt.Printf("Unparseable amounts:           %d\n", s.UnparseableAmountCount)
+	fmt.Printf("Invalid dates:                 %d\n", s.InvalidDateCount)
+	fmt.Printf("Chemicals not found by name:   %d\n", s.UnverifiedLookupCount)
+
+	fmt.Println("\n=== Error Summary ===")
+	fmt.Printf("Total errors:                        %d\n", s.ErrorCount)
+	fmt.Printf("Breakdown:\n")
+	fmt.Printf("\t- Missing chemical name errors:    %d\n", s.ChemicalValidationErrorCount)
+	fmt.Printf("\t- Check chemical errors:           %d\n", s.CheckChemicalErrorCount)
+	fmt.Printf("\t- Create chemical errors:          %d\n", s.CreateChemicalErrorCount)
+	fmt.Printf("\t- Missing chemical ID errors:      %d\n", s.MissingChemicalIDErrorCount)
+	fmt.Printf("\t- Check recipe errors:             %d\n", s.CheckRecipeErrorCount)
+	fmt.Printf("\t- Create recipe errors:            %d\n", s.CreateRecipeErrorCount)
+	fmt.Printf("\t- Check supplier errors:           %d\n", s.CheckSupplierErrorCount)
+	fmt.Printf("\t- Create supplier errors:          %d\n", s.CreateSupplierErrorCount)
+	fmt.Printf("\t- Check location errors:           %d\n", s.CheckLocationErrorCount)
+	fmt.Printf("\t- Create location errors:          %d\n", s.CreateLocationErrorCount)
+	fmt.Printf("\t- Invalid instance errors:         %d\n", s.InstanceValidationErrorCount)
+	fmt.Printf("\t- Check instance errors:           %d\n", s.CheckInstanceErrorCount)
+	fmt.Printf("\t- Missing parent instance errors:  %d\n", s.MissingParentInstanceErrorCount)
+	fmt.Printf("\t- Create instance errors:          %d\n", s.CreateInstanceErrorCount)
+
+	fmt.Println("\n=== Consistency Check ===")
+	fmt.Printf("Is total error count correct?  %t\n", s.IsErrorCountConsistent())
+}
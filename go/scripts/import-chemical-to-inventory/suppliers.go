@@ -0,0 +1,65 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/miru-smart-technologies/Scripts/go/pkg/portal"
+)
+
+// supplierAliases maps a supplier key (see supplierKey) to the canonical name stored in the Portal.
+// Only spelling, spacing and punctuation variants of the same company belong here;
+// distributors ("Fisher (via TCI)") and parent companies are kept as written.
+var supplierAliases = map[string]string{
+	"sigmaaldrich":           "Sigma-Aldrich",
+	"sigamaldrich":           "Sigma-Aldrich",
+	"sigma":                  "Sigma-Aldrich",
+	"milliporesigma":         "MilliporeSigma",
+	"milliportsigma":         "MilliporeSigma",
+	"thermofisher":           "Thermo Fisher Scientific",
+	"thermofiher":            "Thermo Fisher Scientific",
+	"thermofisherscientific": "Thermo Fisher Scientific",
+	"fisher":                 "Fisher Scientific",
+	"fisherscientific":       "Fisher Scientific",
+	"strem":                  "Strem",
+	"eastman":                "Eastman",
+	"hbfuller":               "H.B. Fuller",
+	"kuraray":                "Kuraray",
+	"kurary":                 "Kuraray",
+	"norconorlab":            "NorCo/NorLab",
+	"tci":                    "TCI",
+	"vwr":                    "VWR",
+}
+
+// supplierKey reduces a supplier name to lowercase letters and digits so that
+// "Sigma Aldrich", "Sigma-Aldrich" and "sigma aldrich " end up with the same key
+func supplierKey(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// normaliseSupplierName cleans up a supplier cell and returns the name to use in the Portal
+// together with the key used to cache it, which is the key of that name, so all aliases of a supplier
+// share it. Both are empty when there is no supplier.
+func normaliseSupplierName(raw string) (string, string) {
+	name := strings.Join(strings.Fields(portal.NormaliseText(raw)), " ")
+	// "Sigma?" and "Strem?" mark a supplier the sheet owner was not sure about - keep the guess
+	name = strings.TrimRight(name, "?")
+	name = removeExtraSpace(name)
+
+	key := supplierKey(name)
+	if key == "" {
+		return "", ""
+	}
+
+	if canonical, ok := supplierAliases[key]; ok {
+		return canonical, supplierKey(canonical)
+	}
+
+	return name, key
+}
@@ -0,0 +1,25 @@
+package main
+
+import "testing"
+
+func TestNormaliseSupplierName(t *testing.T) {
+	tests := []struct {
+		raw  string
+		name string
+		key  string
+	}{
+		{"Sigma Aldrich", "Sigma-Aldrich", "sigmaaldrich"},
+		{"sigma", "Sigma-Aldrich", "sigmaaldrich"},
+		{" Sigma? ", "Sigma-Aldrich", "sigmaaldrich"},
+		{"ThermoFiher", "Thermo Fisher Scientific", "thermofisherscientific"},
+		{"thermofisher", "Thermo Fisher Scientific", "thermofisherscientific"},
+		{"Acme  Chemicals", "Acme Chemicals", "acmechemicals"},
+		{" ? ", "", ""},
+	}
+	for _, tt := range tests {
+		name, key := normaliseSupplierName(tt.raw)
+		if name != tt.name || key != tt.key {
+			t.Errorf("normaliseSupplierName(%q) = %q, %q, want %q, %q", tt.raw, name, key, tt.name, tt.key)
+		}
+	}
+}
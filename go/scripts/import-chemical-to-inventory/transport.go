@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Transport is the dangerous goods classification of a chemical; nil fields are sent to the Portal as null
+type Transport struct {
+	UNNumber     *string // UN1120
+	HazardClass  *string // 3, or 8 (6.1) with a subsidiary class
+	PackingGroup *string // I, II or III
+}
+
+// transportClasses are the UN dangerous goods classes and divisions
+var transportClasses = map[string]bool{
+	"1": true, "1.1": true, "1.2": true, "1.3": true, "1.4": true, "1.5": true, "1.6": true,
+	"2.1": true, "2.2": true, "2.3": true,
+	"3":   true,
+	"4.1": true, "4.2": true, "4.3": true,
+	"5.1": true, "5.2": true,
+	"6.1": true, "6.2": true,
+	"7": true, "8": true, "9": true,
+}
+
+// packingGroups maps the spellings of a packing group to its roman numeral
+var packingGroups = map[string]string{
+	"i": "I", "1": "I",
+	"ii": "II", "2": "II",
+	"iii": "III", "3": "III",
+}
+
+var (
+	// unNumber matches "UN1120", "UN 2920" and a bare "1120", with an optional remark after it
+	unNumber = regexp.MustCompile(`^(?:un\s*)?(\d{4})\s*(?:\(([^)]*)\))?$`)
+	// hazardClass matches a class with optional subsidiary classes, e.g. "8 (6.1)" or "2.2 (5.1)"
+	hazardClass = regexp.MustCompile(`^(?:class\s*)?(\d(?:\.\d)?)\s*(?:\(([^)]*)\))?$`)
+	// packingGroupPrefix matches "PG II" and "Packing group II"
+	packingGroupPrefix = regexp.MustCompile(`^(pg|packing group|group)\s*`)
+)
+
+// isNotDangerous reports whether a transport cell says the chemical is not regulated for transport
+func isNotDangerous(text string) bool {
+	switch strings.ToLower(text) {
+	case "not dangerous goods", "not dangerous", "not regulated", "not classified", "n/a", "na", "none", "-":
+		return true
+	}
+	return false
+}
+
+// normaliseUNNumber brings a UN number into the form "UN1120"; a remark after it is returned as a note
+func normaliseUNNumber(raw string) (*string, string, error) {
+	text := strings.Join(strings.Fields(raw), " ")
+	if text == "" || isNotDangerous(text) {
+		return nil, "", nil
+	}
+
+	match := unNumber.FindStringSubmatch(strings.ToLower(text))
+	if match == nil {
+		return nil, "", fmt.Errorf("%q is not a UN number (expected e.g. UN1120)", text)
+	}
+	un := "UN" + match[1]
+
+	note := ""
+	if match[2] != "" {
+		// keep the remark in its original case
+		note = un + ": " + strings.TrimSpace(text[strings.Index(text, "(")+1:strings.LastIndex(text, ")")])
+	}
+	return &un, note, nil
+}
+
+// normaliseHazardClass checks a class cell against the UN classes, e.g. "8 (6.1)" is class 8 with subsidiary class 6.1
+func normaliseHazardClass(raw string) (*string, error) {
+	text := strings.Join(strings.Fields(raw), " ")
+	if text == "" || isNotDangerous(text) {
+		return nil, nil
+	}
+
+	match := hazardClass.FindStringSubmatch(strings.ToLower(text))
+	if match == nil || !transportClasses[match[1]] {
+		return nil, fmt.Errorf("%q is not a dangerous goods class", text)
+	}
+
+	class := match[1]
+	if match[2] != "" {
+		var subsidiaries []string
+		for _, subsidiary := range strings.Split(match[2], ",") {
+			subsidiary = strings.TrimSpace(subsidiary)
+			if !transportClasses[subsidiary] {
+				return nil, fmt.Errorf("%q is not a dangerous goods class, subsidiary class %q is unknown", text, subsidiary)
+			}
+			subsidiaries = append(subsidiaries, subsidiary)
+		}
+		class += " (" + strings.Join(subsidiaries, ", ") + ")"
+	}
+	return &class, nil
+}
+
+// normalisePackingGroup maps a packing group cell to I, II or III
+func normalisePackingGroup(raw string) (*string, error) {
+	text := strings.Join(strings.Fields(raw), " ")
+	if text == "" || isNotDangerous(text) {
+		return nil, nil
+	}
+
+	group, ok := packingGroups[packingGroupPrefix.ReplaceAllString(strings.ToLower(text), "")]
+	if !ok {
+		return nil, fmt.Errorf("%q is not a packing group (expected I, II or III)", text)
+	}
+	return &group, nil
+}
+
+// buildTransport reads the UN number, class and packing group of a row.
+// Cells that cannot be read are returned as problems and kept as text in the notes; a UN number without a class,
+// or a class without a UN number, is also a problem but both values are kept.
+func buildTransport(row []string, cols *Columns) (transport Transport, notes []string, problems []string) {
+	var err error
+	var note string
+
+	value := cols.GetOptionalValueFromRow(row, cols.UnNumber, "")
+	if transport.UNNumber, note, err = normaliseUNNumber(value); err != nil {
+		problems = append(problems, "UN number: "+err.Error())
+		notes = append(notes, "UN number: "+strings.Join(strings.Fields(value), " "))
+	}
+	if note != "" {
+		notes = append(notes, note)
+	}
+
+	value = cols.GetOptionalValueFromRow(row, cols.HazardClass, "")
+	if transport.HazardClass, err = normaliseHazardClass(value); err != nil {
+		problems = append(problems, "class: "+err.Error())
+		notes = append(notes, "Class: "+strings.Join(strings.Fields(value), " "))
+	}
+
+	value = cols.GetOptionalValueFromRow(row, cols.PackingGroup, "")
+	if transport.PackingGroup, err = normalisePackingGroup(value); err != nil {
+		problems = append(problems, "packing group: "+err.Error())
+		notes = append(notes, "Packing group: "+strings.Join(strings.Fields(value), " "))
+	}
+
+	// only cross-check cells that could be read, the others are already reported
+	if len(problems) == 0 {
+		switch {
+		case transport.UNNumber != nil && transport.HazardClass == nil:
+			problems = append(problems, fmt.Sprintf("UN number %s has no class", *transport.UNNumber))
+		case transport.UNNumber == nil && transport.HazardClass != nil:
+			problems = append(problems, fmt.Sprintf("class %s has no UN number", *transport.HazardClass))
+		case transport.UNNumber == nil && transport.PackingGroup != nil:
+			problems = append(problems, fmt.Sprintf("packing group %s has no UN number", *transport.PackingGroup))
+		}
+	}
+
+	return transport, notes, problems
+}
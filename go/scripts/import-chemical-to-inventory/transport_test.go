@@ -0,0 +1,76 @@
+package main
+
+import "testing"
+
+func TestNormaliseTransport(t *testing.T) {
+	unNumbers := map[string]string{"UN1120": "UN1120", "UN 2920": "UN2920", "1170": "UN1170", " un3206 ": "UN3206", "UN3234 (note cannot transport by air)": "UN3234"}
+	for raw, want := range unNumbers {
+		got, _, err := normaliseUNNumber(raw)
+		if err != nil || got == nil || *got != want {
+			t.Errorf("normaliseUNNumber(%q) = %v, %v; want %q", raw, got, err, want)
+		}
+	}
+	if _, note, _ := normaliseUNNumber("UN3234 (note cannot transport by air)"); note != "UN3234: note cannot transport by air" {
+		t.Errorf("remark kept as %q", note)
+	}
+
+	classes := map[string]string{"3": "3", "8 (6.1)": "8 (6.1)", "4.2 (8)": "4.2 (8)", "2.2(5.1)": "2.2 (5.1)", "Class 9": "9"}
+	for raw, want := range classes {
+		got, err := normaliseHazardClass(raw)
+		if err != nil || got == nil || *got != want {
+			t.Errorf("normaliseHazardClass(%q) = %v, %v; want %q", raw, got, err, want)
+		}
+	}
+
+	groups := map[string]string{"II": "II", "iii": "III", "PG I": "I", "2": "II"}
+	for raw, want := range groups {
+		got, err := normalisePackingGroup(raw)
+		if err != nil || got == nil || *got != want {
+			t.Errorf("normalisePackingGroup(%q) = %v, %v; want %q", raw, got, err, want)
+		}
+	}
+
+	for _, raw := range []string{"Not dangerous goods", "not dangerous goods", "n/a", ""} {
+		un, _, err1 := normaliseUNNumber(raw)
+		class, err2 := normaliseHazardClass(raw)
+		group, err3 := normalisePackingGroup(raw)
+		if un != nil || class != nil || group != nil || err1 != nil || err2 != nil || err3 != nil {
+			t.Errorf("%q: want null UN number, class and packing group", raw)
+		}
+	}
+
+	if _, _, err := normaliseUNNumber("UN112"); err == nil {
+		t.Errorf("normaliseUNNumber(UN112): want an error")
+	}
+	for _, raw := range []string{"4 (6.1)", "10", "8 (flammable)"} {
+		if _, err := normaliseHazardClass(raw); err == nil {
+			t.Errorf("normaliseHazardClass(%q): want an error", raw)
+		}
+	}
+	if _, err := normalisePackingGroup("IV"); err == nil {
+		t.Errorf("normalisePackingGroup(IV): want an error")
+	}
+}
+
+func TestBuildTransportCrossChecks(t *testing.T) {
+	cols := NewColumns()
+	cols.UnNumber, cols.HazardClass, cols.PackingGroup = 0, 1, 2
+
+	tests := []struct {
+		row      []string
+		problems int
+	}{
+		{[]string{"UN1120", "3", "II"}, 0},
+		{[]string{"Not dangerous goods", "n/a", "n/a"}, 0},
+		{[]string{"UN1120", "n/a", "II"}, 1},
+		{[]string{"Not dangerous goods", "3", "n/a"}, 1},
+		{[]string{"", "", "III"}, 1},
+		{[]string{"UN1120", "4 (6.1)", "II"}, 1},
+	}
+	for _, tt := range tests {
+		_, _, problems := buildTransport(tt.row, cols)
+		if len(problems) != tt.problems {
+			t.Errorf("buildTransport(%q) problems = %q, want %d", tt.row, problems, tt.problems)
+		}
+	}
+}
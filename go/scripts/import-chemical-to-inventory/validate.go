@@ -0,0 +1,204 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// ValidateOptions holds the command-line flags of the validate-mapping command
+type ValidateOptions struct {
+	MappingFile string
+	CsvFile     string // overrides RAW_CSV in the mapping file
+	LayoutsDir  string
+	Rows        int // number of sample rows to show per field
+}
+
+func ParseValidateFlags(args []string) (*ValidateOptions, error) {
+	opts := &ValidateOptions{}
+
+	fs := flag.NewFlagSet("validate-mapping", flag.ContinueOnError)
+	fs.StringVar(&opts.MappingFile, "mapping", "chemical_inventory.env", "mapping env file")
+	fs.StringVar(&opts.CsvFile, "csv", "", "input CSV to check the mapping against (default: RAW_CSV from the mapping file)")
+	fs.StringVar(&opts.LayoutsDir, "layouts", "layouts", "directory of the known sheet layouts (*.env)")
+	fs.IntVar(&opts.Rows, "rows", 3, "number of sample values to show per field")
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if opts.Rows < 0 {
+		return nil, fmt.Errorf("-rows must not be negative")
+	}
+
+	return opts, nil
+}
+
+// readCsvSample reads the header row and up to n data rows of a CSV file
+func readCsvSample(filename string, n int) ([]string, [][]string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	header, err := reader.Read()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read header: %w", err)
+	}
+
+	var rows [][]string
+	for len(rows) < n {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read row %d: %w", len(rows)+2, err)
+		}
+		rows = append(rows, row)
+	}
+
+	return header, rows, nil
+}
+
+// sampleText shortens a cell to one line for the validation report
+func sampleText(value string) string {
+	value = strings.Join(strings.Fields(value), " ")
+	if runes := []rune(value); len(runes) > 50 {
+		value = string(runes[:47]) + "..."
+	}
+	return fmt.Sprintf("%q", value)
+}
+
+// RunValidateMapping prints the column mapping the import would use for the CSV and returns the number of problems found
+func RunValidateMapping(opts *ValidateOptions) (int, error) {
+	cols := NewColumns()
+	if err := cols.LoadFromEnv(opts.MappingFile); err != nil {
+		return 0, err
+	}
+	if opts.CsvFile != "" {
+		cols.RawCsv = opts.CsvFile
+	}
+	if cols.RawCsv == "" {
+		return 0, fmt.Errorf("no input CSV - set RAW_CSV in the mapping file or use -csv")
+	}
+
+	layouts, err := LoadLayouts(opts.LayoutsDir)
+	if err != nil {
+		return 0, err
+	}
+	header, rows, err := readCsvSample(cols.RawCsv, opts.Rows)
+	if err != nil {
+		return 0, err
+	}
+
+	var problems []string
+	problem := func(format string, args ...any) {
+		msg := fmt.Sprintf(format, args...)
+		problems = append(problems, msg)
+		fmt.Printf("\tPROBLEM: %s\n", msg)
+	}
+
+	fmt.Printf("Mapping file: %s\n", opts.MappingFile)
+	fmt.Printf("CSV file:     %s (%d columns)\n", cols.RawCsv, len(header))
+
+	layout, err := DetectLayout(layouts, header)
+	if err != nil {
+		fmt.Printf("Sheet layout: none\n")
+		problem("%v", err)
+	} else {
+		fmt.Printf("Sheet layout: %s (%s)\n", layout.Name, layout.File)
+	}
+
+	// keys that are not a known field are typos, e.g. COLUMN_LOCATON_NAME, and would be ignored by the import
+	known := map[string]bool{}
+	for _, field := range cols.columnFields() {
+		known[field.key] = true
+	}
+	var unknown []string
+	for key := range cols.env {
+		if strings.HasPrefix(key, "COLUMN_") && !known[key] {
+			unknown = append(unknown, key+" in "+opts.MappingFile)
+		}
+	}
+	if layout != nil {
+		for key := range layout.Mappings {
+			if !known[key] {
+				unknown = append(unknown, key+" in "+layout.File)
+			}
+		}
+	}
+	sort.Strings(unknown)
+	for _, key := range unknown {
+		problem("unknown mapping key %s", key)
+	}
+
+	fieldsByColumn := map[int][]string{}
+	for _, field := range cols.columnFields() {
+		value, source := cols.mappingValue(field.key, layout)
+		fmt.Println()
+		if value == "" {
+			fmt.Printf("%-38s not mapped\n", field.key)
+			if field.index == &cols.ChemicalName {
+				problem("%s is required", field.key)
+			}
+			continue
+		}
+
+		index := -1
+		if isColumnLetter(value) {
+			index = LetterToIndex(value)
+		} else if index, err = findHeader(header, value); err != nil {
+			fmt.Printf("%-38s %q (%s)\n", field.key, value, source)
+			problem("%s: %v", field.key, err)
+			continue
+		}
+
+		letter := IndexToLetter(index)
+		if index >= len(header) {
+			fmt.Printf("%-38s %s (%s)\n", field.key, letter, source)
+			problem("%s: column %s is out of range, the CSV has columns A to %s", field.key, letter, IndexToLetter(len(header)-1))
+			continue
+		}
+
+		fmt.Printf("%-38s %-3s %-40s (%s)\n", field.key, letter, sampleText(header[index]), source)
+		if strings.TrimSpace(header[index]) == "" {
+			problem("%s: column %s has no header - is the letter right?", field.key, letter)
+		}
+		fieldsByColumn[index] = append(fieldsByColumn[index], field.key)
+
+		for i, row := range rows {
+			sample := ""
+			if index < len(row) {
+				sample = row[index]
+			}
+			fmt.Printf("\trow %d: %s\n", i+2, sampleText(sample))
+		}
+	}
+
+	fmt.Println()
+	var columns []int
+	for index := range fieldsByColumn {
+		columns = append(columns, index)
+	}
+	sort.Ints(columns)
+	for _, index := range columns {
+		if keys := fieldsByColumn[index]; len(keys) > 1 {
+			problem("column %s is mapped by more than one field: %s", IndexToLetter(index), strings.Join(keys, ", "))
+		}
+	}
+
+	if len(problems) == 0 {
+		fmt.Println("Mapping OK")
+	} else {
+		fmt.Printf("%d problem(s) found\n", len(problems))
+	}
+
+	return len(problems), nil
+}
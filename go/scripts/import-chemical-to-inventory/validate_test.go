@@ -0,0 +1,48 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestValidateMappingCheckedInLayouts(t *testing.T) {
+	for _, fixture := range importFixtures {
+		problems, err := RunValidateMapping(&ValidateOptions{
+			MappingFile: "chemical_inventory.env",
+			CsvFile:     fixture.csvFile,
+			LayoutsDir:  "layouts",
+			Rows:        2,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if problems != 0 {
+			t.Errorf("%s: %d problems", fixture.csvFile, problems)
+		}
+	}
+}
+
+func TestValidateMappingFindsProblems(t *testing.T) {
+	mappingFile := filepath.Join(t.TempDir(), "mapping.env")
+	mapping := `
+RAW_CSV = chemicals-05-20-16-55.csv
+COLUMN_LABEL = C
+COLUMN_PARENT_ID = ZZ
+COLUMN_LOCATON_NAME = M
+COLUMN_OWNER = Y
+COLUMN_LOT_NUMBER = "lot number"
+`
+	if err := os.WriteFile(mappingFile, []byte(mapping), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	problems, err := RunValidateMapping(&ValidateOptions{MappingFile: mappingFile, LayoutsDir: "layouts", Rows: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// duplicate column C, ZZ out of range, unknown key, column Y without header, missing header
+	if problems != 5 {
+		t.Errorf("found %d problems, want 5", problems)
+	}
+}
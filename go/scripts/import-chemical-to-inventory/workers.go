@@ -0,0 +1,282 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/miru-smart-technologies/Scripts/go/pkg/portal"
+)
+
+// importRun is what the rows of a run share. The rows can be processed by several workers at once,
+// so everything a row writes to goes through a lock.
+type importRun struct {
+	cols            *Columns
+	chemicalNames   *ChemicalNames
+	locationAliases *LocationAliases
+	owners          *Owners
+
+	portal    portal.PortalClient // the Portal itself, without retries
+	policy    portal.RetryPolicy
+	catalogue *CatalogueClient // nil without -preload, or if the catalogue couldn't be loaded
+	planner   *DryRunClient    // set in dry-run mode, where the clients of the workers plan the creates instead
+	resumeLog *ResumeLog       // set when resuming a previous run
+
+	locks         *KeyLocks
+	verifyLookups bool // look every created chemical up by name again
+
+	mu          sync.Mutex
+	supplierIDs map[string]string // supplier IDs resolved so far, keyed by supplierKey, so every supplier is looked up only once per run
+	locationIDs map[string]string // location IDs resolved so far, keyed by canonical location name
+}
+
+// workerClient returns the client a worker imports its rows through. Every worker retries on its own, so the
+// attempts it logs are those of its own calls; the catalogue and the dry-run plan are shared by all workers.
+func (run *importRun) workerClient() (portal.PortalClient, *portal.RetryClient) {
+	retrier := portal.NewRetryClient(run.portal, run.policy)
+
+	var client portal.PortalClient = retrier
+	if run.catalogue != nil {
+		client = run.catalogue.WithClient(client)
+	}
+	if run.planner != nil {
+		client = run.planner.WithClient(client)
+	}
+	return client, retrier
+}
+
+func (run *importRun) supplierID(key string) (string, bool) {
+	run.mu.Lock()
+	defer run.mu.Unlock()
+
+	id, ok := run.supplierIDs[key]
+	return id, ok
+}
+
+func (run *importRun) setSupplierID(key string, id string) {
+	run.mu.Lock()
+	defer run.mu.Unlock()
+
+	run.supplierIDs[key] = id
+}
+
+func (run *importRun) locationID(name string) (string, bool) {
+	run.mu.Lock()
+	defer run.mu.Unlock()
+
+	id, ok := run.locationIDs[name]
+	return id, ok
+}
+
+func (run *importRun) setLocationID(name string, id string) {
+	run.mu.Lock()
+	defer run.mu.Unlock()
+
+	run.locationIDs[name] = id
+}
+
+// rowResult is what processing a row produced: its processed log entries and its counts
+type rowResult struct {
+	rowNum  int
+	log     bytes.Buffer
+	summary Summary
+}
+
+// processRows reads the rows of reader and processes them with the given number of workers. The log entries
+// of each row are written to processedLog once all rows before it are written, so the log stays in row order.
+//
+// The rows are read before any is processed and split into groups (see groupRows). A worker processes the rows
+// of a group one after the other in row order, so the rows of a chemical find and create it like they do with
+// one worker, whichever group is picked up first.
+func (run *importRun) processRows(reader *csv.Reader, processedLog io.Writer, workers int) (*Summary, error) {
+	results := make(chan *rowResult)
+
+	var rows []rowJob
+	var unreadable []*rowResult
+	for rowNum := 1; ; rowNum++ {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			fmt.Printf("Error reading row %d: %v - skipping\n", rowNum, err)
+			result := &rowResult{rowNum: rowNum}
+			writer := &LogWriter{Writer: csv.NewWriter(&result.log)}
+			writeProcessedLog(writer.withoutCall(), rowNum, "Read row", "cannot read", "", err.Error())
+			writer.Flush()
+			result.summary.ErrorCount++
+			unreadable = append(unreadable, result)
+			continue
+		}
+		rows = append(rows, rowJob{rowNum: rowNum, row: row})
+	}
+	rowCount := len(rows) + len(unreadable)
+
+	groups := make(chan []rowJob)
+	var wg sync.WaitGroup
+	for i := 0; i < max(workers, 1); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			client, retrier := run.workerClient()
+			for group := range groups {
+				for _, job := range group {
+					retrier.TakeAttempts() // calls of the previous row that were not logged are not this row's
+					result := &rowResult{rowNum: job.rowNum}
+					writer := &LogWriter{Writer: csv.NewWriter(&result.log), retrier: retrier}
+					run.processRow(client, retrier, writer, &result.summary, job.rowNum, job.row)
+					writer.Flush()
+					results <- result
+				}
+			}
+		}()
+	}
+
+	go func() {
+		for _, result := range unreadable {
+			results <- result
+		}
+		for _, group := range run.groupRows(rows) {
+			groups <- group
+		}
+		close(groups)
+		wg.Wait()
+		close(results)
+	}()
+
+	summary := &Summary{}
+	pending := map[int]*rowResult{}
+	next := 1
+	var writeErr error
+	for result := range results {
+		pending[result.rowNum] = result
+		for result, ok := pending[next]; ok; result, ok = pending[next] {
+			if _, err := processedLog.Write(result.log.Bytes()); err != nil && writeErr == nil {
+				writeErr = fmt.Errorf("failed to write processed log: %w", err)
+			}
+			summary.Add(&result.summary)
+			delete(pending, next)
+			next++
+		}
+	}
+	if writeErr != nil {
+		return nil, writeErr
+	}
+
+	summary.RowCount = rowCount
+	return summary, nil
+}
+
+// rowJob is a row of the CSV to process
+type rowJob struct {
+	rowNum int
+	row    []string
+}
+
+// groupRows splits rows into the groups of rows that depend on each other: rows that share a chemical name,
+// a CAS number or a CIID, or point at the CIID of another row as their parent, end up in one group,
+// also through other rows. The groups are in the order of their first row, the rows of a group in row order.
+func (run *importRun) groupRows(rows []rowJob) [][]rowJob {
+	parent := make([]int, len(rows))
+	var root func(i int) int
+	root = func(i int) int {
+		if parent[i] != i {
+			parent[i] = root(parent[i])
+		}
+		return parent[i]
+	}
+
+	firstRow := map[string]int{} // key -> index of the first row with it
+	for i, job := range rows {
+		parent[i] = i
+		for _, key := range run.rowKeys(job.row) {
+			first, ok := firstRow[key]
+			if !ok {
+				firstRow[key] = i
+				continue
+			}
+			// the group of the earlier row takes over, so a group's root is always its first row
+			a, b := root(first), root(i)
+			parent[max(a, b)] = min(a, b)
+		}
+	}
+
+	var groups [][]rowJob
+	groupOf := map[int]int{} // root -> index in groups
+	for i, job := range rows {
+		r := root(i)
+		g, ok := groupOf[r]
+		if !ok {
+			g = len(groups)
+			groupOf[r] = g
+			groups = append(groups, nil)
+		}
+		groups[g] = append(groups[g], job)
+	}
+	return groups
+}
+
+// rowKeys returns the keys that tie a row to the rows it depends on, see groupRows
+func (run *importRun) rowKeys(row []string) []string {
+	cols := run.cols
+
+	var keys []string
+	name := cols.GetOptionalValueFromRow(row, cols.ChemicalName, "")
+	if name = run.chemicalNames.Resolve(name); name != "" {
+		keys = append(keys, "chemical "+chemicalSpellingKey(name))
+	}
+	if cas, _, err := parseCasCell(cols.GetOptionalValueFromRow(row, cols.CasNumber, "")); err == nil && cas != "" {
+		keys = append(keys, "CAS "+cas)
+	}
+	for _, column := range []int{cols.Ciid, cols.ParentID} {
+		if ciid := ciidText(cols.GetOptionalValueFromRow(row, column, "")); ciid != "" {
+			keys = append(keys, "CIID "+ciid)
+		}
+	}
+	return keys
+}
+
+// KeyLocks serialises the rows of different groups that work on the same record, e.g. so that only the first
+// of the rows of a supplier creates it and the others find it
+type KeyLocks struct {
+	mu    sync.Mutex
+	locks map[string]*sync.Mutex
+}
+
+func NewKeyLocks() *KeyLocks {
+	return &KeyLocks{locks: map[string]*sync.Mutex{}}
+}
+
+// Lock locks the given keys and returns the function that unlocks them, which may be called more than once.
+// Keys are always locked in the same order, so two rows locking overlapping keys can't deadlock.
+func (k *KeyLocks) Lock(keys ...string) (unlock func()) {
+	keys = append([]string(nil), keys...)
+	sort.Strings(keys)
+
+	var locked []*sync.Mutex
+	for i, key := range keys {
+		if i > 0 && key == keys[i-1] {
+			continue
+		}
+		k.mu.Lock()
+		lock, ok := k.locks[key]
+		if !ok {
+			lock = &sync.Mutex{}
+			k.locks[key] = lock
+		}
+		k.mu.Unlock()
+
+		lock.Lock()
+		locked = append(locked, lock)
+	}
+
+	return sync.OnceFunc(func() {
+		for i := len(locked) - 1; i >= 0; i-- {
+			locked[i].Unlock()
+		}
+	})
+}